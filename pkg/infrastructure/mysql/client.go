@@ -34,14 +34,24 @@ type TransactionalClient interface {
 	Connection(ctx context.Context) (TransactionalConnection, error)
 }
 
+// NewTransactionalClient wraps db opened by caller, e.g. with another driver in tests
+func NewTransactionalClient(db *sqlx.DB) TransactionalClient {
+	return &transactionalClient{DB: db, stats: &libraryStats{}}
+}
+
 type transactionalClient struct {
 	*sqlx.DB
+	stats *libraryStats
 }
 
 func (client *transactionalClient) BeginTransaction() (Transaction, error) {
 	return client.Beginx()
 }
 
+func (client *transactionalClient) libraryStats() *libraryStats {
+	return client.stats
+}
+
 func (client *transactionalClient) Connection(ctx context.Context) (TransactionalConnection, error) {
 	connx, err := client.Connx(ctx)
 	if err != nil {
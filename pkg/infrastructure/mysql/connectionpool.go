@@ -6,28 +6,41 @@ import (
 )
 
 type ConnectionPool interface {
+	// TransactionalConnection shares connection between contexts with the same scope,
+	// context without scope gets connection of its own
 	TransactionalConnection(ctx context.Context) (TransactionalConnection, error)
 }
 
 func NewConnectionPool(client TransactionalClient) ConnectionPool {
 	return &connectionPool{
 		client: client,
-		pool:   make(map[context.Context]*sharedConnection),
+		stats:  libraryStatsOf(client),
 	}
 }
 
 type connectionPool struct {
 	client TransactionalClient
+	stats  *libraryStats
 
-	mu   sync.Mutex
-	pool map[context.Context]*sharedConnection
+	mu sync.Mutex
 }
 
 func (cp *connectionPool) TransactionalConnection(ctx context.Context) (TransactionalConnection, error) {
+	if _, ok := scopeFromContext(ctx); !ok {
+		return cp.client.Connection(ctx)
+	}
+
 	cp.mu.Lock()
 	defer cp.mu.Unlock()
 
-	conn, ok := cp.pool[ctx]
+	conn, ok := loadFromScope[*sharedConnection](ctx, cp)
+	for ok && conn.exclusive != nil {
+		exclusive := conn.exclusive
+		cp.mu.Unlock()
+		<-exclusive
+		cp.mu.Lock()
+		conn, ok = loadFromScope[*sharedConnection](ctx, cp)
+	}
 	if ok {
 		conn.count++
 	}
@@ -38,39 +51,71 @@ func (cp *connectionPool) TransactionalConnection(ctx context.Context) (Transact
 		}
 		conn = &sharedConnection{
 			TransactionalConnection: c,
+			pool:                    cp,
 			ctx:                     ctx,
 			count:                   1,
-			releaseCallback:         cp.release,
 		}
-		cp.pool[ctx] = conn
+		storeToScope(ctx, cp, conn)
+		cp.stats.sharedConnections.Add(1)
 	}
 	return conn, nil
 }
 
+func (cp *connectionPool) libraryStats() *libraryStats {
+	return cp.stats
+}
+
 func (cp *connectionPool) release(ctx context.Context) error {
 	cp.mu.Lock()
 	defer cp.mu.Unlock()
 
-	conn, ok := cp.pool[ctx]
-	if ok {
+	conn, ok := loadFromScope[*sharedConnection](ctx, cp)
+	if !ok {
 		return nil
 	}
 	if conn.count == 1 {
 		err := conn.TransactionalConnection.Close()
-		delete(cp.pool, ctx)
+		deleteFromScope(ctx, cp)
+		cp.stats.sharedConnections.Add(-1)
 		return err
 	}
 	conn.count--
 	return nil
 }
 
+// runExclusive runs f when connection is held by single holder, connection is not shared until f returns
+func (cp *connectionPool) runExclusive(conn *sharedConnection, f func() error) (bool, error) {
+	cp.mu.Lock()
+	if conn.count != 1 || conn.exclusive != nil {
+		cp.mu.Unlock()
+		return false, nil
+	}
+	exclusive := make(chan struct{})
+	conn.exclusive = exclusive
+	cp.mu.Unlock()
+
+	defer func() {
+		cp.mu.Lock()
+		defer cp.mu.Unlock()
+		conn.exclusive = nil
+		close(exclusive)
+	}()
+	return true, f()
+}
+
 type sharedConnection struct {
 	TransactionalConnection
-	ctx             context.Context
-	count           int
-	releaseCallback func(ctx context.Context) error
+	pool  *connectionPool
+	ctx   context.Context
+	count int
+	// exclusive is closed when connection may be shared again
+	exclusive chan struct{}
 }
 
 func (sc *sharedConnection) Close() error {
-	return sc.releaseCallback(sc.ctx)
+	return sc.pool.release(sc.ctx)
+}
+
+func (sc *sharedConnection) runExclusive(f func() error) (bool, error) {
+	return sc.pool.runExclusive(sc, f)
 }
@@ -1,14 +1,21 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
-	// include mysql driver
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
+var (
+	ErrInvalidConfig    = errors.New("invalid mysql config")
+	errDBNotInitialized = errors.New("db not initialized")
+)
+
 func NewConnector() Connector {
 	return &connector{}
 }
@@ -18,46 +25,138 @@ type Connector interface {
 	Close() error
 
 	TransactionalClient() TransactionalClient
+	HealthReporter
 }
 
 type Config struct {
-	MaxConnections     int
-	ConnectionLifetime time.Duration
+	MaxConnections        int
+	ConnectionLifetime    time.Duration
+	MaxIdleConnections    int
+	ConnectionMaxIdleTime time.Duration
+	PingTimeout           time.Duration
+	// StartupRetry retries initial ping, e.g. when database starts after application
+	StartupRetry RetryConfig
+}
+
+func (cfg Config) Validate() error {
+	var err error
+	if cfg.MaxConnections < 0 {
+		err = errors.Join(err, fmt.Errorf("%w: max connections %d is negative", ErrInvalidConfig, cfg.MaxConnections))
+	}
+	if cfg.MaxConnections > 0 && cfg.MaxIdleConnections > cfg.MaxConnections {
+		err = errors.Join(err, fmt.Errorf("%w: max idle connections %d exceed max connections %d", ErrInvalidConfig, cfg.MaxIdleConnections, cfg.MaxConnections))
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{name: "connection lifetime", value: cfg.ConnectionLifetime},
+		{name: "connection max idle time", value: cfg.ConnectionMaxIdleTime},
+		{name: "ping timeout", value: cfg.PingTimeout},
+		{name: "startup retry interval", value: cfg.StartupRetry.Backoff.InitialInterval},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			err = errors.Join(err, fmt.Errorf("%w: %s %s is negative", ErrInvalidConfig, d.name, d.value))
+		}
+	}
+	return err
 }
 
 type connector struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	stats libraryStats
 }
 
 func (c *connector) Open(dsn DSN, cfg Config) error {
 	var err error
-	c.db, err = sqlx.Open("mysql", dsn.String())
-	if err != nil {
-		return err
+	c.db, err = openDB(dsn, cfg)
+	return err
+}
+
+func (c *connector) Close() error {
+	if c.db != nil {
+		return c.db.Close()
+	}
+	return errDBNotInitialized
+}
+
+func (c *connector) TransactionalClient() TransactionalClient {
+	return &transactionalClient{DB: c.db, stats: &c.stats}
+}
+
+func (c *connector) Health(ctx context.Context) error {
+	if c.db == nil {
+		return errDBNotInitialized
+	}
+	return c.db.PingContext(ctx)
+}
+
+func (c *connector) Stats() Stats {
+	var stats Stats
+	if c.db != nil {
+		stats.DB = c.db.Stats()
 	}
+	c.stats.fill(&stats)
+	return stats
+}
 
-	c.db.SetMaxOpenConns(cfg.MaxConnections)
-	c.db.SetConnMaxLifetime(cfg.ConnectionLifetime)
+func openDB(dsn DSN, cfg Config) (*sqlx.DB, error) {
+	db, err := newDB(dsn, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	pingError := c.db.Ping()
+	pingError := pingWithRetry(db, cfg)
 	if pingError != nil {
-		err = c.db.Close()
+		err = db.Close()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return pingError
+		return nil, pingError
 	}
 
-	return nil
+	return db, nil
 }
 
-func (c *connector) Close() error {
-	if c.db != nil {
-		return c.db.Close()
+// newDB does not connect to server, connections are opened on demand
+func newDB(dsn DSN, cfg Config) (*sqlx.DB, error) {
+	mysqlConfig, err := dsn.Config()
+	if err != nil {
+		return nil, err
 	}
-	return errors.New("db not initialized")
+	mysqlConnector, err := mysql.NewConnector(mysqlConfig)
+	if err != nil {
+		return nil, err
+	}
+	db := sqlx.NewDb(sql.OpenDB(mysqlConnector), "mysql")
+
+	db.SetMaxOpenConns(cfg.MaxConnections)
+	db.SetConnMaxLifetime(cfg.ConnectionLifetime)
+	if cfg.MaxIdleConnections != 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConnections)
+	}
+	db.SetConnMaxIdleTime(cfg.ConnectionMaxIdleTime)
+
+	return db, nil
 }
 
-func (c *connector) TransactionalClient() TransactionalClient {
-	return &transactionalClient{c.db}
+func pingWithRetry(db *sqlx.DB, cfg Config) error {
+	for attempt := 1; ; attempt++ {
+		err := ping(db, cfg.PingTimeout)
+		if err == nil || attempt >= cfg.StartupRetry.MaxAttempts {
+			return err
+		}
+		time.Sleep(cfg.StartupRetry.Backoff.Interval(attempt))
+	}
+}
+
+func ping(db *sqlx.DB, timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return db.PingContext(ctx)
 }
@@ -1,14 +1,277 @@
 package mysql
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultCharset   = "utf8mb4"
+	defaultCollation = "utf8mb4_unicode_ci"
+	redactedPassword = "***"
+)
+
+var errInvalidTLSCertificate = errors.New("invalid tls certificate")
+
+// params represented by DSN fields are not kept in DSN.Params
+var dsnFieldParams = map[string]struct{}{
+	"tls":               {},
+	"timeout":           {},
+	"readTimeout":       {},
+	"writeTimeout":      {},
+	"loc":               {},
+	"interpolateParams": {},
+}
 
 type DSN struct {
 	User     string
 	Password string
-	Host     string
+	// Host may contain port, e.g. "localhost:3306"
+	Host string
+	Port int
+	// Socket is path to unix socket, used instead of Host and Port
+	Socket   string
 	Database string
+
+	TLS *TLSConfig
+
+	Timeout           time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	Location          *time.Location
+	InterpolateParams bool
+	// Params are appended to DSN as is, they override defaults: charset=utf8mb4, collation=utf8mb4_unicode_ci, parseTime=true
+	Params map[string]string
+}
+
+type TLSConfig struct {
+	// Name refers to "true", "skip-verify", "preferred" or config registered with mysql.RegisterTLSConfig,
+	// other fields are ignored when Name is set
+	Name string
+
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+func ParseDSN(s string) (DSN, error) {
+	cfg, err := mysql.ParseDSN(s)
+	if err != nil {
+		return DSN{}, err
+	}
+
+	dsn := DSN{
+		User:              cfg.User,
+		Password:          cfg.Passwd,
+		Database:          cfg.DBName,
+		Timeout:           cfg.Timeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		Location:          cfg.Loc,
+		InterpolateParams: cfg.InterpolateParams,
+	}
+
+	if cfg.Net == "unix" {
+		dsn.Socket = cfg.Addr
+	} else {
+		host, port, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return DSN{}, err
+		}
+		dsn.Host = host
+		dsn.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return DSN{}, err
+		}
+	}
+
+	if cfg.TLSConfig != "" && cfg.TLSConfig != "false" {
+		dsn.TLS = &TLSConfig{Name: cfg.TLSConfig}
+	}
+
+	params, err := rawDSNParams(s)
+	if err != nil {
+		return DSN{}, err
+	}
+	for key, values := range params {
+		if _, ok := dsnFieldParams[key]; ok {
+			continue
+		}
+		if dsn.Params == nil {
+			dsn.Params = make(map[string]string)
+		}
+		dsn.Params[key] = values[len(values)-1]
+	}
+
+	return dsn, nil
+}
+
+func (dsn *DSN) Validate() error {
+	var err error
+	if dsn.User == "" {
+		err = errors.Join(err, fmt.Errorf("%w: user is empty", ErrInvalidConfig))
+	}
+	if dsn.Host == "" && dsn.Socket == "" {
+		err = errors.Join(err, fmt.Errorf("%w: neither host nor socket is set", ErrInvalidConfig))
+	}
+	if dsn.Port < 0 || dsn.Port > 65535 {
+		err = errors.Join(err, fmt.Errorf("%w: port %d is out of range", ErrInvalidConfig, dsn.Port))
+	}
+	if dsn.Database == "" {
+		err = errors.Join(err, fmt.Errorf("%w: database is empty", ErrInvalidConfig))
+	}
+	return err
+}
+
+// Config builds driver config, custom TLS config is registered in driver
+func (dsn *DSN) Config() (*mysql.Config, error) {
+	tlsName, err := dsn.TLS.register()
+	if err != nil {
+		return nil, err
+	}
+	return mysql.ParseDSN(dsn.format(dsn.Password, tlsName))
+}
+
+// FormatDSN returns DSN with password, use String to log DSN
+func (dsn *DSN) FormatDSN() (string, error) {
+	cfg, err := dsn.Config()
+	if err != nil {
+		return "", err
+	}
+	return cfg.FormatDSN(), nil
 }
 
+// String returns DSN with redacted password
 func (dsn *DSN) String() string {
-	return fmt.Sprintf("%s:%s@(%s)/%s?charset=utf8mb4&collation=utf8mb4_unicode_ci&parseTime=true", dsn.User, dsn.Password, dsn.Host, dsn.Database)
+	password := ""
+	if dsn.Password != "" {
+		password = redactedPassword
+	}
+	// custom TLS config is not registered yet, so it is set after parsing
+	cfg, err := mysql.ParseDSN(dsn.format(password, ""))
+	if err != nil {
+		return dsn.format(password, dsn.TLS.name())
+	}
+	cfg.TLSConfig = dsn.TLS.name()
+	return cfg.FormatDSN()
+}
+
+func (dsn *DSN) format(password, tlsName string) string {
+	cfg := mysql.NewConfig()
+	cfg.User = dsn.User
+	cfg.Passwd = password
+	cfg.DBName = dsn.Database
+	if dsn.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = dsn.Socket
+	} else {
+		cfg.Net = "tcp"
+		cfg.Addr = dsn.Host
+		if dsn.Port != 0 {
+			cfg.Addr = net.JoinHostPort(dsn.Host, strconv.Itoa(dsn.Port))
+		}
+	}
+	cfg.Params = map[string]string{"charset": defaultCharset}
+	cfg.Collation = defaultCollation
+	cfg.ParseTime = true
+	cfg.TLSConfig = tlsName
+	cfg.Timeout = dsn.Timeout
+	cfg.ReadTimeout = dsn.ReadTimeout
+	cfg.WriteTimeout = dsn.WriteTimeout
+	if dsn.Location != nil {
+		cfg.Loc = dsn.Location
+	}
+	cfg.InterpolateParams = dsn.InterpolateParams
+
+	result := cfg.FormatDSN()
+	if len(dsn.Params) == 0 {
+		return result
+	}
+
+	keys := make([]string, 0, len(dsn.Params))
+	for key := range dsn.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, key+"="+url.QueryEscape(dsn.Params[key]))
+	}
+
+	separator := "?"
+	if strings.Contains(result[strings.LastIndex(result, "/"):], "?") {
+		separator = "&"
+	}
+	return result + separator + strings.Join(params, "&")
+}
+
+func rawDSNParams(s string) (url.Values, error) {
+	i := strings.LastIndex(s, "/")
+	_, query, ok := strings.Cut(s[i+1:], "?")
+	if !ok {
+		return nil, nil
+	}
+	return url.ParseQuery(query)
+}
+
+func (cfg *TLSConfig) name() string {
+	if cfg == nil {
+		return ""
+	}
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%t", cfg.CAFile, cfg.CertFile, cfg.KeyFile, cfg.ServerName, cfg.InsecureSkipVerify)))
+	return "custom-" + hex.EncodeToString(hash[:8])
+}
+
+func (cfg *TLSConfig) register() (string, error) {
+	if cfg == nil || cfg.Name != "" {
+		return cfg.name(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec // explicitly requested by configuration
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return "", err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("%w: no certificates in %s", errInvalidTLSCertificate, cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return "", err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	name := cfg.name()
+	err := mysql.RegisterTLSConfig(name, tlsConfig)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
 }
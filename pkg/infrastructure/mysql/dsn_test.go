@@ -0,0 +1,97 @@
+package mysql_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestDSNStringRedactsPassword(t *testing.T) {
+	dsn := mysql.DSN{User: "app", Password: "secret", Host: "db", Port: 3306, Database: "orders"}
+
+	s := dsn.String()
+	if strings.Contains(s, "secret") || !strings.HasPrefix(s, "app:***@tcp(db:3306)/orders?") {
+		t.Fatalf("expected DSN with redacted password, got %s", s)
+	}
+
+	formatted, err := dsn.FormatDSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := gomysql.ParseDSN(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Passwd != "secret" || cfg.Addr != "db:3306" || cfg.DBName != "orders" {
+		t.Fatalf("expected FormatDSN to keep password, got %s", formatted)
+	}
+	if !cfg.ParseTime || cfg.Collation != "utf8mb4_unicode_ci" || cfg.Params["charset"] != "utf8mb4" {
+		t.Fatalf("expected default params, got %s", formatted)
+	}
+}
+
+func TestParseDSNRoundTrip(t *testing.T) {
+	dsn, err := mysql.ParseDSN("app:secret@tcp(db:3307)/orders?timeout=5s&readTimeout=1s&interpolateParams=true&tls=skip-verify&sql_mode=ANSI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn.User != "app" || dsn.Password != "secret" || dsn.Host != "db" || dsn.Port != 3307 || dsn.Database != "orders" {
+		t.Fatalf("unexpected DSN %+v", dsn)
+	}
+	if dsn.Timeout != time.Second*5 || dsn.ReadTimeout != time.Second || !dsn.InterpolateParams {
+		t.Fatalf("unexpected DSN timeouts %+v", dsn)
+	}
+	if dsn.TLS == nil || dsn.TLS.Name != "skip-verify" {
+		t.Fatalf("expected TLS config name, got %+v", dsn.TLS)
+	}
+	if len(dsn.Params) != 1 || dsn.Params["sql_mode"] != "ANSI" {
+		t.Fatalf("expected only params not represented by fields, got %v", dsn.Params)
+	}
+
+	formatted, err := dsn.FormatDSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mysql.ParseDSN(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.String() != dsn.String() {
+		t.Fatalf("expected %s, got %s", dsn.String(), parsed.String())
+	}
+}
+
+func TestParseDSNOfSocket(t *testing.T) {
+	dsn, err := mysql.ParseDSN("app@unix(/var/run/mysqld/mysqld.sock)/orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn.Socket != "/var/run/mysqld/mysqld.sock" || dsn.Host != "" {
+		t.Fatalf("expected socket DSN, got %+v", dsn)
+	}
+	if s := dsn.String(); !strings.HasPrefix(s, "app@unix(/var/run/mysqld/mysqld.sock)/orders") {
+		t.Fatalf("unexpected socket DSN %s", s)
+	}
+}
+
+func TestDSNValidate(t *testing.T) {
+	err := (&mysql.DSN{Port: 70000}).Validate()
+	if !errors.Is(err, mysql.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	for _, field := range []string{"user", "host", "port", "database"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s to be reported, got %v", field, err)
+		}
+	}
+
+	err = (&mysql.DSN{User: "app", Socket: "/tmp/mysql.sock", Database: "orders"}).Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
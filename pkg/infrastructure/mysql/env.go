@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LoadFromEnv builds DSN and Config from environment variables with common prefix, e.g. for prefix "MYSQL":
+// MYSQL_DSN is parsed first, then MYSQL_USER, MYSQL_PASSWORD, MYSQL_HOST, MYSQL_PORT, MYSQL_SOCKET, MYSQL_DATABASE,
+// MYSQL_TLS, MYSQL_TLS_CA_FILE, MYSQL_TLS_CERT_FILE, MYSQL_TLS_KEY_FILE, MYSQL_TLS_SERVER_NAME, MYSQL_TLS_SKIP_VERIFY,
+// MYSQL_TIMEOUT, MYSQL_READ_TIMEOUT, MYSQL_WRITE_TIMEOUT, MYSQL_LOCATION, MYSQL_INTERPOLATE_PARAMS, MYSQL_PARAMS
+// override its parts and MYSQL_MAX_CONNECTIONS, MYSQL_CONNECTION_LIFETIME, MYSQL_MAX_IDLE_CONNECTIONS,
+// MYSQL_CONNECTION_MAX_IDLE_TIME, MYSQL_PING_TIMEOUT, MYSQL_STARTUP_RETRY_ATTEMPTS, MYSQL_STARTUP_RETRY_INTERVAL,
+// MYSQL_STARTUP_RETRY_MAX_INTERVAL fill Config
+func LoadFromEnv(prefix string) (DSN, Config, error) {
+	l := envLoader{prefix: prefix}
+
+	var dsn DSN
+	if s, ok := l.lookup("DSN"); ok {
+		parsed, err := ParseDSN(s)
+		l.fail("DSN", err)
+		dsn = parsed
+	}
+	l.string("USER", &dsn.User)
+	l.string("PASSWORD", &dsn.Password)
+	l.string("HOST", &dsn.Host)
+	l.int("PORT", &dsn.Port)
+	l.string("SOCKET", &dsn.Socket)
+	l.string("DATABASE", &dsn.Database)
+	l.duration("TIMEOUT", &dsn.Timeout)
+	l.duration("READ_TIMEOUT", &dsn.ReadTimeout)
+	l.duration("WRITE_TIMEOUT", &dsn.WriteTimeout)
+	l.location("LOCATION", &dsn.Location)
+	l.bool("INTERPOLATE_PARAMS", &dsn.InterpolateParams)
+	l.params("PARAMS", &dsn.Params)
+	l.tls(&dsn.TLS)
+
+	var cfg Config
+	l.int("MAX_CONNECTIONS", &cfg.MaxConnections)
+	l.duration("CONNECTION_LIFETIME", &cfg.ConnectionLifetime)
+	l.int("MAX_IDLE_CONNECTIONS", &cfg.MaxIdleConnections)
+	l.duration("CONNECTION_MAX_IDLE_TIME", &cfg.ConnectionMaxIdleTime)
+	l.duration("PING_TIMEOUT", &cfg.PingTimeout)
+	cfg.StartupRetry.Backoff = DefaultRetryConfig.Backoff
+	l.int("STARTUP_RETRY_ATTEMPTS", &cfg.StartupRetry.MaxAttempts)
+	l.duration("STARTUP_RETRY_INTERVAL", &cfg.StartupRetry.Backoff.InitialInterval)
+	l.duration("STARTUP_RETRY_MAX_INTERVAL", &cfg.StartupRetry.Backoff.MaxInterval)
+
+	err := errors.Join(l.err, dsn.Validate(), cfg.Validate())
+	if err != nil {
+		return DSN{}, Config{}, err
+	}
+	return dsn, cfg, nil
+}
+
+type envLoader struct {
+	prefix string
+	err    error
+}
+
+func (l *envLoader) lookup(name string) (string, bool) {
+	return os.LookupEnv(l.prefix + "_" + name)
+}
+
+func (l *envLoader) fail(name string, err error) {
+	if err != nil {
+		l.err = errors.Join(l.err, fmt.Errorf("%w: %s_%s: %s", ErrInvalidConfig, l.prefix, name, err))
+	}
+}
+
+func (l *envLoader) string(name string, dest *string) {
+	if s, ok := l.lookup(name); ok {
+		*dest = s
+	}
+}
+
+func (l *envLoader) int(name string, dest *int) {
+	if s, ok := l.lookup(name); ok {
+		v, err := strconv.Atoi(s)
+		l.fail(name, err)
+		*dest = v
+	}
+}
+
+func (l *envLoader) bool(name string, dest *bool) {
+	if s, ok := l.lookup(name); ok {
+		v, err := strconv.ParseBool(s)
+		l.fail(name, err)
+		*dest = v
+	}
+}
+
+func (l *envLoader) duration(name string, dest *time.Duration) {
+	if s, ok := l.lookup(name); ok {
+		v, err := time.ParseDuration(s)
+		l.fail(name, err)
+		*dest = v
+	}
+}
+
+func (l *envLoader) location(name string, dest **time.Location) {
+	if s, ok := l.lookup(name); ok {
+		v, err := time.LoadLocation(s)
+		l.fail(name, err)
+		*dest = v
+	}
+}
+
+// params are url encoded, e.g. "sql_mode=ANSI&time_zone=%27%2B00:00%27"
+func (l *envLoader) params(name string, dest *map[string]string) {
+	s, ok := l.lookup(name)
+	if !ok {
+		return
+	}
+	values, err := url.ParseQuery(s)
+	l.fail(name, err)
+	for key, v := range values {
+		if *dest == nil {
+			*dest = make(map[string]string)
+		}
+		(*dest)[key] = v[len(v)-1]
+	}
+}
+
+func (l *envLoader) tls(dest **TLSConfig) {
+	var cfg TLSConfig
+	if *dest != nil {
+		cfg = **dest
+	}
+	l.string("TLS", &cfg.Name)
+	l.string("TLS_CA_FILE", &cfg.CAFile)
+	l.string("TLS_CERT_FILE", &cfg.CertFile)
+	l.string("TLS_KEY_FILE", &cfg.KeyFile)
+	l.string("TLS_SERVER_NAME", &cfg.ServerName)
+	l.bool("TLS_SKIP_VERIFY", &cfg.InsecureSkipVerify)
+	if cfg != (TLSConfig{}) {
+		*dest = &cfg
+	}
+}
@@ -0,0 +1,87 @@
+package mysql_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestLoadFromEnvOverridesDSNParts(t *testing.T) {
+	t.Setenv("TEST_MYSQL_DSN", "app:secret@tcp(db:3306)/orders?sql_mode=ANSI")
+	t.Setenv("TEST_MYSQL_HOST", "replica")
+	t.Setenv("TEST_MYSQL_PASSWORD", "other")
+	t.Setenv("TEST_MYSQL_TIMEOUT", "3s")
+	t.Setenv("TEST_MYSQL_PARAMS", "time_zone=%27%2B00:00%27")
+	t.Setenv("TEST_MYSQL_TLS", "skip-verify")
+	t.Setenv("TEST_MYSQL_MAX_CONNECTIONS", "10")
+	t.Setenv("TEST_MYSQL_MAX_IDLE_CONNECTIONS", "5")
+	t.Setenv("TEST_MYSQL_STARTUP_RETRY_ATTEMPTS", "4")
+	t.Setenv("TEST_MYSQL_STARTUP_RETRY_INTERVAL", "100ms")
+
+	dsn, cfg, err := mysql.LoadFromEnv("TEST_MYSQL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dsn.User != "app" || dsn.Password != "other" || dsn.Host != "replica" || dsn.Port != 3306 || dsn.Database != "orders" {
+		t.Fatalf("unexpected DSN %+v", dsn)
+	}
+	if dsn.Timeout != time.Second*3 {
+		t.Fatalf("expected timeout 3s, got %s", dsn.Timeout)
+	}
+	if dsn.Params["sql_mode"] != "ANSI" || dsn.Params["time_zone"] != "'+00:00'" {
+		t.Fatalf("unexpected params %v", dsn.Params)
+	}
+	if dsn.TLS == nil || dsn.TLS.Name != "skip-verify" {
+		t.Fatalf("unexpected TLS config %+v", dsn.TLS)
+	}
+
+	if cfg.MaxConnections != 10 || cfg.MaxIdleConnections != 5 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if cfg.StartupRetry.MaxAttempts != 4 || cfg.StartupRetry.Backoff.InitialInterval != time.Millisecond*100 {
+		t.Fatalf("unexpected startup retry %+v", cfg.StartupRetry)
+	}
+	if cfg.StartupRetry.Backoff.MaxInterval != mysql.DefaultRetryConfig.Backoff.MaxInterval {
+		t.Fatalf("expected default startup retry max interval, got %s", cfg.StartupRetry.Backoff.MaxInterval)
+	}
+}
+
+func TestLoadFromEnvReportsAllInvalidVariables(t *testing.T) {
+	t.Setenv("TEST_MYSQL_USER", "app")
+	t.Setenv("TEST_MYSQL_HOST", "db")
+	t.Setenv("TEST_MYSQL_DATABASE", "orders")
+	t.Setenv("TEST_MYSQL_PORT", "mysql")
+	t.Setenv("TEST_MYSQL_PING_TIMEOUT", "soon")
+	t.Setenv("TEST_MYSQL_MAX_CONNECTIONS", "-1")
+
+	_, _, err := mysql.LoadFromEnv("TEST_MYSQL")
+	if !errors.Is(err, mysql.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	for _, reported := range []string{"TEST_MYSQL_PORT", "TEST_MYSQL_PING_TIMEOUT", "max connections"} {
+		if !strings.Contains(err.Error(), reported) {
+			t.Errorf("expected %s to be reported, got %v", reported, err)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	err := mysql.Config{MaxConnections: 2, MaxIdleConnections: 3, PingTimeout: -time.Second}.Validate()
+	if !errors.Is(err, mysql.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	for _, reported := range []string{"max idle connections", "ping timeout"} {
+		if !strings.Contains(err.Error(), reported) {
+			t.Errorf("expected %s to be reported, got %v", reported, err)
+		}
+	}
+
+	err = mysql.Config{MaxIdleConnections: 3}.Validate()
+	if err != nil {
+		t.Fatalf("expected unlimited connections to allow idle connections, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package mysql
+
+import "github.com/jmoiron/sqlx"
+
+// WithoutScope hides scope of context like internal queries of the package do
+var WithoutScope = withoutScope
+
+// NewFencingToken creates token of lock key like LockFactory does
+func NewFencingToken(lockName, lockKey string, value int64) FencingToken {
+	return FencingToken{
+		LockName: lockName,
+		Value:    value,
+		lockKey:  lockKey,
+	}
+}
+
+// LockKey is key of MySQL named lock taken for lock name in database
+var LockKey = lockKey
+
+// NewReplicatedConnectorOf creates ReplicatedConnector of opened databases like Open does
+func NewReplicatedConnectorOf(primary *sqlx.DB, replicas []*sqlx.DB, replicaConfig ReplicaConfig) ReplicatedConnector {
+	c := &replicatedConnector{primary: connector{db: primary}}
+	for _, db := range replicas {
+		c.replicas = append(c.replicas, &replica{db: db})
+	}
+	c.start(replicaConfig)
+	return c
+}
@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	// include embed for fencing token schema
+	_ "embed"
+	"errors"
+)
+
+var (
+	ErrFencingTokenStale = errors.New("fencing token stale")
+	// ErrFencingTokenNotIssued is returned for token not issued by LockFactory of the package, e.g. by fake,
+	// since such token has no lock key to check
+	ErrFencingTokenNotIssued = errors.New("fencing token not issued by lock factory")
+)
+
+// FencingTokenSchema is DDL of the table storing fencing tokens of locks
+//
+//go:embed fencingtoken.sql
+var FencingTokenSchema string
+
+// FencingToken increases with every acquisition of lock, so writes of holder which lost lock can be rejected
+type FencingToken struct {
+	LockName string
+	Value    int64
+
+	lockKey string
+}
+
+// ExecFenced executes query within unit of work when token is the latest token of its lock, the token row stays
+// share locked until the transaction ends, so the next holder can not get new token meanwhile
+func ExecFenced(ctx context.Context, uow UnitOfWork, token FencingToken, query string, args ...interface{}) (sql.Result, error) {
+	if token.lockKey == "" {
+		return nil, ErrFencingTokenNotIssued
+	}
+
+	client := uow.ClientContext()
+	const sqlQuery = "SELECT token FROM lock_fencing_token WHERE name = ? LOCK IN SHARE MODE"
+	var latest int64
+	err := client.GetContext(ctx, &latest, sqlQuery, token.lockKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFencingTokenStale
+	}
+	if err != nil {
+		return nil, err
+	}
+	if latest != token.Value {
+		return nil, ErrFencingTokenStale
+	}
+	return client.ExecContext(ctx, query, args...)
+}
+
+// issueFencingTokens client must not be bound to transaction, otherwise rolled back token may be issued again
+func issueFencingTokens(ctx context.Context, client ClientContext, lockNames, lockKeys []string) ([]FencingToken, error) {
+	const sqlQuery = `
+		INSERT INTO lock_fencing_token (name, token) VALUES (?, LAST_INSERT_ID(1))
+		ON DUPLICATE KEY UPDATE token = LAST_INSERT_ID(token + 1)`
+
+	tokens := make([]FencingToken, 0, len(lockKeys))
+	for i, lockKey := range lockKeys {
+		result, err := client.ExecContext(ctx, sqlQuery, lockKey)
+		if err != nil {
+			return nil, err
+		}
+		value, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, FencingToken{
+			LockName: lockNames[i],
+			Value:    value,
+			lockKey:  lockKey,
+		})
+	}
+	return tokens, nil
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS lock_fencing_token
+(
+    name  VARCHAR(255)    NOT NULL,
+    token BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_bin;
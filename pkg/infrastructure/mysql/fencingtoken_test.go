@@ -0,0 +1,93 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+const fencedQuery = "UPDATE item SET id = ? WHERE id = ?"
+
+func TestExecFencedExecutesQueryWithLatestToken(t *testing.T) {
+	uow := newFencingUnitOfWork(t, 7, nil)
+
+	_, err := mysql.ExecFenced(context.Background(), uow, mysql.NewFencingToken("order.42", "order.42", 7), fencedQuery, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFencedQueryExecuted(t, uow, true)
+}
+
+func TestExecFencedRejectsStaleToken(t *testing.T) {
+	for name, tc := range map[string]struct {
+		latest int64
+		err    error
+	}{
+		"newer token issued": {latest: 8},
+		"token not stored":   {err: sql.ErrNoRows},
+	} {
+		t.Run(name, func(t *testing.T) {
+			uow := newFencingUnitOfWork(t, tc.latest, tc.err)
+
+			_, err := mysql.ExecFenced(context.Background(), uow, mysql.NewFencingToken("order.42", "order.42", 7), fencedQuery, 2, 1)
+			if !errors.Is(err, mysql.ErrFencingTokenStale) {
+				t.Fatalf("expected ErrFencingTokenStale, got %v", err)
+			}
+			assertFencedQueryExecuted(t, uow, false)
+		})
+	}
+}
+
+func TestExecFencedRejectsTokenNotIssuedByLockFactory(t *testing.T) {
+	locks := mysql.NewReentrantLockFactory(mysqltest.NewLockFactory())
+	lock, err := locks.NewLock(context.Background(), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+	tokens := lock.FencingTokens()
+	if len(tokens) != 1 {
+		t.Fatalf("expected token of wrapped factory, got %v", tokens)
+	}
+	uow := newFencingUnitOfWork(t, tokens[0].Value, nil)
+
+	_, err = mysql.ExecFenced(context.Background(), uow, tokens[0], fencedQuery, 2, 1)
+	if !errors.Is(err, mysql.ErrFencingTokenNotIssued) {
+		t.Fatalf("expected ErrFencingTokenNotIssued, got %v", err)
+	}
+	if queries := uow.ClientContext().(*mysqltest.ClientContext).Queries(); len(queries) != 0 {
+		t.Fatalf("expected no queries, got %v", queries)
+	}
+}
+
+// newFencingUnitOfWork returns unit of work reading latest token or err from lock_fencing_token
+func newFencingUnitOfWork(t *testing.T, latest int64, err error) mysql.UnitOfWork {
+	t.Helper()
+	factory := mysqltest.NewUnitOfWorkFactory()
+	factory.Client.GetFunc = func(_ context.Context, dest interface{}, _ string, _ ...interface{}) error {
+		*dest.(*int64) = latest
+		return err
+	}
+	uow, err := factory.UnitOfWork(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = uow.Complete(nil)
+	})
+	return uow
+}
+
+func assertFencedQueryExecuted(t *testing.T, uow mysql.UnitOfWork, expected bool) {
+	t.Helper()
+	queries := uow.ClientContext().(*mysqltest.ClientContext).Queries()
+	executed := len(queries) == 2 && queries[1].Query == fencedQuery
+	if executed != expected {
+		t.Fatalf("expected fenced query executed to be %v, got queries %v", expected, queries)
+	}
+}
@@ -0,0 +1,49 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	healthStatusOK          = "ok"
+	healthStatusUnavailable = "unavailable"
+)
+
+type HealthReporter interface {
+	Health(ctx context.Context) error
+	Stats() Stats
+}
+
+func NewHealthHandler(reporter HealthReporter) http.Handler {
+	return &healthHandler{reporter: reporter}
+}
+
+type healthHandler struct {
+	reporter HealthReporter
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Stats  Stats  `json:"stats"`
+}
+
+func (h *healthHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	response := healthResponse{
+		Status: healthStatusOK,
+		Stats:  h.reporter.Stats(),
+	}
+	statusCode := http.StatusOK
+	err := h.reporter.Health(request.Context())
+	if err != nil {
+		response.Status = healthStatusUnavailable
+		response.Error = err.Error()
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(response)
+}
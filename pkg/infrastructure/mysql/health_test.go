@@ -0,0 +1,80 @@
+package mysql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+type healthReporter struct {
+	err   error
+	stats mysql.Stats
+}
+
+func (r *healthReporter) Health(context.Context) error {
+	return r.err
+}
+
+func (r *healthReporter) Stats() mysql.Stats {
+	return r.stats
+}
+
+func TestHealthHandlerReportsStats(t *testing.T) {
+	reporter := &healthReporter{stats: mysql.Stats{OpenTransactions: 2, HeldLocks: 1}}
+
+	response := serveHealth(t, reporter)
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", response.Code)
+	}
+	body := decodeHealth(t, response)
+	if body.Status != "ok" || body.Error != "" {
+		t.Fatalf("unexpected health %+v", body)
+	}
+	if body.Stats.OpenTransactions != 2 || body.Stats.HeldLocks != 1 {
+		t.Fatalf("unexpected stats %+v", body.Stats)
+	}
+}
+
+func TestHealthHandlerReportsUnavailableDatabase(t *testing.T) {
+	reporter := &healthReporter{err: errTest}
+
+	response := serveHealth(t, reporter)
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", response.Code)
+	}
+	body := decodeHealth(t, response)
+	if body.Status != "unavailable" || body.Error != errTest.Error() {
+		t.Fatalf("unexpected health %+v", body)
+	}
+}
+
+type healthBody struct {
+	Status string      `json:"status"`
+	Error  string      `json:"error"`
+	Stats  mysql.Stats `json:"stats"`
+}
+
+func serveHealth(t *testing.T, reporter mysql.HealthReporter) *httptest.ResponseRecorder {
+	t.Helper()
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/health", nil)
+	mysql.NewHealthHandler(reporter).ServeHTTP(response, request)
+	if contentType := response.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("expected json response, got %s", contentType)
+	}
+	return response
+}
+
+func decodeHealth(t *testing.T, response *httptest.ResponseRecorder) healthBody {
+	t.Helper()
+	var body healthBody
+	err := json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
@@ -0,0 +1,115 @@
+package mysql_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	applogger "github.com/tss-calculator/go-lib/pkg/application/logger"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest/sqlite"
+)
+
+func newClient(t *testing.T) *sqlite.Client {
+	t.Helper()
+	client, err := sqlite.NewTransactionalClient("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	_, err = client.DB().Exec("CREATE TABLE item (id INTEGER PRIMARY KEY)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func insertItem(t *testing.T, ctx context.Context, uow mysql.UnitOfWork, id int) {
+	t.Helper()
+	_, err := uow.ClientContext().ExecContext(ctx, "INSERT INTO item (id) VALUES (?)", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertItems(t *testing.T, client *sqlite.Client, expected ...int) {
+	t.Helper()
+	var ids []int
+	err := client.DB().Select(&ids, "SELECT id FROM item ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected items %v, got %v", expected, ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected items %v, got %v", expected, ids)
+		}
+	}
+}
+
+func connectionID(t *testing.T, ctx context.Context, client mysql.ClientContext) int64 {
+	t.Helper()
+	var id int64
+	err := client.GetContext(ctx, &id, "SELECT CONNECTION_ID()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// newContext returns context cancelled when test ends
+func newContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// testLogger records errors of Error and Warning and sends them to logged, when it is set
+type testLogger struct {
+	logged chan error
+
+	mu     sync.Mutex
+	errors []error
+}
+
+func (l *testLogger) WithField(string, interface{}) applogger.Logger {
+	return l
+}
+
+func (l *testLogger) WithFields(applogger.Fields) applogger.Logger {
+	return l
+}
+
+func (l *testLogger) Info(...interface{}) {}
+
+func (l *testLogger) Error(err error, _ ...interface{}) {
+	l.record(err)
+}
+
+func (l *testLogger) Warning(err error, _ ...interface{}) {
+	l.record(err)
+}
+
+func (l *testLogger) Debug(...interface{}) {}
+
+func (l *testLogger) Errors() []error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]error(nil), l.errors...)
+}
+
+func (l *testLogger) record(err error) {
+	l.mu.Lock()
+	l.errors = append(l.errors, err)
+	l.mu.Unlock()
+	if l.logged != nil {
+		l.logged <- err
+	}
+}
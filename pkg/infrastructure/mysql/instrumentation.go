@@ -0,0 +1,223 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"time"
+
+	applogger "github.com/tss-calculator/go-lib/pkg/application/logger"
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+	tracecontext "github.com/tss-calculator/go-lib/pkg/infrastructure/simpletrace/context"
+)
+
+const (
+	QueryOperationQuery    = "query"
+	QueryOperationQueryRow = "query_row"
+	QueryOperationExec     = "exec"
+	QueryOperationSelect   = "select"
+	QueryOperationGet      = "get"
+)
+
+type QueryEvent struct {
+	Operation string
+	Query     string
+	Args      []interface{}
+	Duration  time.Duration
+	// Rows is number of affected rows for exec and number of fetched rows for select and get, -1 when unknown
+	Rows    int64
+	Err     error
+	TraceID string
+}
+
+type QueryObserver interface {
+	ObserveQuery(ctx context.Context, event QueryEvent)
+}
+
+func InstrumentClientContext(client ClientContext, observer QueryObserver) ClientContext {
+	return &instrumentedClientContext{
+		client:   client,
+		observer: observer,
+	}
+}
+
+func InstrumentTransaction(tx Transaction, observer QueryObserver) Transaction {
+	return &instrumentedTransaction{
+		instrumentedClientContext: instrumentedClientContext{client: tx, observer: observer},
+		tx:                        tx,
+	}
+}
+
+func InstrumentTransactionalConnection(conn TransactionalConnection, observer QueryObserver) TransactionalConnection {
+	return &instrumentedConnection{
+		instrumentedClientContext: instrumentedClientContext{client: conn, observer: observer},
+		conn:                      conn,
+	}
+}
+
+// InstrumentTransactionalClient instruments client with all connections and transactions opened by it,
+// pass it to NewConnectionPool to instrument units of work and locks
+func InstrumentTransactionalClient(client TransactionalClient, observer QueryObserver) TransactionalClient {
+	return &instrumentedClient{
+		instrumentedClientContext: instrumentedClientContext{client: client, observer: observer},
+		transactionalClient:       client,
+	}
+}
+
+func NewSlowQueryLogger(logger applogger.Logger, threshold time.Duration) QueryObserver {
+	return &slowQueryLogger{
+		logger:    logger,
+		threshold: threshold,
+	}
+}
+
+type instrumentedClientContext struct {
+	client   ClientContext
+	observer QueryObserver
+}
+
+func (c *instrumentedClientContext) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := c.client.QueryContext(ctx, query, args...)
+	c.observe(ctx, QueryOperationQuery, query, args, start, -1, err)
+	return rows, err
+}
+
+func (c *instrumentedClientContext) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := c.client.QueryRowContext(ctx, query, args...)
+	c.observe(ctx, QueryOperationQueryRow, query, args, start, -1, row.Err())
+	return row
+}
+
+func (c *instrumentedClientContext) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := c.client.ExecContext(ctx, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		if affected, affectedErr := result.RowsAffected(); affectedErr == nil {
+			rows = affected
+		}
+	}
+	c.observe(ctx, QueryOperationExec, query, args, start, rows, err)
+	return result, err
+}
+
+func (c *instrumentedClientContext) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := c.client.SelectContext(ctx, dest, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		if v := reflect.Indirect(reflect.ValueOf(dest)); v.Kind() == reflect.Slice {
+			rows = int64(v.Len())
+		}
+	}
+	c.observe(ctx, QueryOperationSelect, query, args, start, rows, err)
+	return err
+}
+
+func (c *instrumentedClientContext) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := c.client.GetContext(ctx, dest, query, args...)
+	rows := int64(0)
+	if err == nil {
+		rows = 1
+	}
+	c.observe(ctx, QueryOperationGet, query, args, start, rows, err)
+	return err
+}
+
+func (c *instrumentedClientContext) observe(
+	ctx context.Context,
+	operation, query string,
+	args []interface{},
+	start time.Time,
+	rows int64,
+	err error,
+) {
+	event := QueryEvent{
+		Operation: operation,
+		Query:     query,
+		Args:      args,
+		Duration:  time.Since(start),
+		Rows:      rows,
+		Err:       err,
+	}
+	if trace, ok := maybe.Just(tracecontext.GetTrace(ctx)); ok {
+		event.TraceID = trace.TraceID
+	}
+	c.observer.ObserveQuery(ctx, event)
+}
+
+type instrumentedTransaction struct {
+	instrumentedClientContext
+	tx Transaction
+}
+
+func (tx *instrumentedTransaction) Commit() error {
+	return tx.tx.Commit()
+}
+
+func (tx *instrumentedTransaction) Rollback() error {
+	return tx.tx.Rollback()
+}
+
+type instrumentedConnection struct {
+	instrumentedClientContext
+	conn TransactionalConnection
+}
+
+func (conn *instrumentedConnection) BeginTransaction(ctx context.Context, opts *sql.TxOptions) (Transaction, error) {
+	tx, err := conn.conn.BeginTransaction(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return InstrumentTransaction(tx, conn.observer), nil
+}
+
+func (conn *instrumentedConnection) Close() error {
+	return conn.conn.Close()
+}
+
+type instrumentedClient struct {
+	instrumentedClientContext
+	transactionalClient TransactionalClient
+}
+
+func (client *instrumentedClient) BeginTransaction() (Transaction, error) {
+	tx, err := client.transactionalClient.BeginTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return InstrumentTransaction(tx, client.observer), nil
+}
+
+func (client *instrumentedClient) Connection(ctx context.Context) (TransactionalConnection, error) {
+	conn, err := client.transactionalClient.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return InstrumentTransactionalConnection(conn, client.observer), nil
+}
+
+func (client *instrumentedClient) libraryStats() *libraryStats {
+	return libraryStatsOf(client.transactionalClient)
+}
+
+type slowQueryLogger struct {
+	logger    applogger.Logger
+	threshold time.Duration
+}
+
+func (l *slowQueryLogger) ObserveQuery(_ context.Context, event QueryEvent) {
+	if event.Duration < l.threshold {
+		return
+	}
+	l.logger.WithFields(applogger.Fields{
+		"query":     event.Query,
+		"operation": event.Operation,
+		"duration":  event.Duration.String(),
+		"rows":      event.Rows,
+		"trace_id":  event.TraceID,
+	}).Warning(event.Err, "slow query")
+}
@@ -0,0 +1,103 @@
+package mysql_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	tracecontext "github.com/tss-calculator/go-lib/pkg/infrastructure/simpletrace/context"
+)
+
+// queryRecorder records events of observed queries
+type queryRecorder struct {
+	mu     sync.Mutex
+	events []mysql.QueryEvent
+}
+
+func (r *queryRecorder) ObserveQuery(_ context.Context, event mysql.QueryEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *queryRecorder) Events() []mysql.QueryEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]mysql.QueryEvent(nil), r.events...)
+}
+
+func TestInstrumentedClientObservesQueriesOfUnitOfWork(t *testing.T) {
+	client := newClient(t)
+	recorder := &queryRecorder{}
+	pool := mysql.NewConnectionPool(mysql.InstrumentTransactionalClient(client, recorder))
+	factory := mysql.NewUnitOfWorkFactory(pool, nil)
+	ctx := tracecontext.SetTrace(newContext(t), tracecontext.Trace{TraceID: "trace"})
+
+	uow, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, uow.Context(), uow, 1)
+	insertItem(t, uow.Context(), uow, 2)
+	var ids []int
+	err = uow.ClientContext().SelectContext(uow.Context(), &ids, "SELECT id FROM item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = uow.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 observed queries, got %+v", events)
+	}
+	for i, operation := range []string{mysql.QueryOperationExec, mysql.QueryOperationExec, mysql.QueryOperationSelect} {
+		if events[i].Operation != operation {
+			t.Fatalf("expected %s, got %+v", operation, events[i])
+		}
+		if events[i].TraceID != "trace" {
+			t.Fatalf("expected trace id of context, got %+v", events[i])
+		}
+	}
+	if events[0].Rows != 1 || events[2].Rows != 2 {
+		t.Fatalf("unexpected rows %d and %d", events[0].Rows, events[2].Rows)
+	}
+}
+
+func TestInstrumentedClientObservesFailedQueries(t *testing.T) {
+	client := newClient(t)
+	recorder := &queryRecorder{}
+	instrumented := mysql.InstrumentClientContext(client, recorder)
+
+	var id int
+	err := instrumented.GetContext(newContext(t), &id, "SELECT id FROM missing")
+	if err == nil {
+		t.Fatal("expected query to fail")
+	}
+
+	events := recorder.Events()
+	if len(events) != 1 || events[0].Operation != mysql.QueryOperationGet || events[0].Err != err || events[0].Rows != 0 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+func TestSlowQueryLoggerLogsQueriesExceedingThreshold(t *testing.T) {
+	logger := &testLogger{}
+	observer := mysql.NewSlowQueryLogger(logger, time.Second)
+	ctx := newContext(t)
+
+	observer.ObserveQuery(ctx, mysql.QueryEvent{Query: "SELECT 1", Duration: time.Millisecond})
+	if len(logger.Errors()) != 0 {
+		t.Fatal("expected fast query not to be logged")
+	}
+
+	observer.ObserveQuery(ctx, mysql.QueryEvent{Query: "SELECT SLEEP(1)", Duration: time.Second, Err: errTest})
+	errs := logger.Errors()
+	if len(errs) != 1 || errs[0] != errTest {
+		t.Fatalf("expected slow query to be logged with its error, got %v", errs)
+	}
+}
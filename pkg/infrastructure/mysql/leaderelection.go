@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	applogger "github.com/tss-calculator/go-lib/pkg/application/logger"
+)
+
+var DefaultLeaderElectionConfig = LeaderElectionConfig{
+	RetryInterval: time.Second * 5,
+}
+
+type LeaderElectionConfig struct {
+	LockName string
+	// RetryInterval is time between attempts to acquire leadership, DefaultLeaderElectionConfig is used when it is zero
+	RetryInterval time.Duration
+}
+
+// LeaderElectionCallback is run while instance is leader, ctx is derived from ctx of Run
+// and it is cancelled with ErrLockLost cause when leadership is lost
+type LeaderElectionCallback func(ctx context.Context)
+
+type LeaderElection interface {
+	// Run campaigns for leadership until ctx is done and runs callback on every acquired leadership,
+	// leadership is resigned when callback returns
+	Run(ctx context.Context, callback LeaderElectionCallback)
+	IsLeader() bool
+	// Changes receives leadership state on transition, only the latest state is kept for slow receiver
+	Changes() <-chan bool
+}
+
+// NewLeaderElection creates LeaderElection holding named lock of lockFactory while instance is leader,
+// lockFactory must report lost locks, e.g. with heartbeat enabled, so callback is stopped on leadership loss
+func NewLeaderElection(lockFactory LockFactory, logger applogger.Logger, config LeaderElectionConfig) LeaderElection {
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultLeaderElectionConfig.RetryInterval
+	}
+	return &leaderElection{
+		lockFactory: lockFactory,
+		logger:      logger.WithField("lock", config.LockName),
+		config:      config,
+		changes:     make(chan bool, 1),
+	}
+}
+
+type leaderElection struct {
+	lockFactory LockFactory
+	logger      applogger.Logger
+	config      LeaderElectionConfig
+	leader      atomic.Bool
+	changes     chan bool
+}
+
+func (election *leaderElection) Run(ctx context.Context, callback LeaderElectionCallback) {
+	for {
+		// leadership lock is not shared with scope of ctx, so it is not reentered by other locks of the scope
+		lock, err := election.lockFactory.TryLock(withoutScope(ctx), election.config.LockName)
+		if err == nil {
+			election.lead(ctx, lock, callback)
+		} else if !errors.Is(err, ErrLockBusy) && ctx.Err() == nil {
+			election.logger.Error(err, "failed acquire leadership")
+		}
+		if sleep(ctx, election.config.RetryInterval) != nil {
+			return
+		}
+	}
+}
+
+func (election *leaderElection) IsLeader() bool {
+	return election.leader.Load()
+}
+
+func (election *leaderElection) Changes() <-chan bool {
+	return election.changes
+}
+
+// lead runs callback until it returns or lock is lost, callback context is derived from ctx of Run instead of
+// lock context, so units of work and locks of callback use scope of ctx, and it is cancelled with ErrLockLost cause
+// when lock is lost
+func (election *leaderElection) lead(ctx context.Context, lock Lock, callback LeaderElectionCallback) {
+	election.setLeader(true)
+	election.logger.Info("acquired leadership")
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		callback(ctx)
+	}()
+
+	lost := false
+	select {
+	case <-done:
+	case <-lock.Lost():
+		lost = true
+	case <-ctx.Done():
+	}
+	if lost {
+		cancel(ErrLockLost)
+	} else {
+		cancel(nil)
+	}
+	<-done
+
+	election.setLeader(false)
+	// lost lock is unlocked as well to free its resources, e.g. connection, so its error is expected
+	err := lock.Unlock()
+	if lost {
+		election.logger.Warning(ErrLockLost, "lost leadership")
+		return
+	}
+	if err != nil {
+		election.logger.Error(err, "failed release leadership lock")
+	}
+	election.logger.Info("resigned leadership")
+}
+
+// setLeader replaces state not yet received from Changes, so transition is never blocked by receiver
+func (election *leaderElection) setLeader(leader bool) {
+	election.leader.Store(leader)
+	for {
+		select {
+		case election.changes <- leader:
+			return
+		default:
+		}
+		select {
+		case <-election.changes:
+		default:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+func TestLeaderElectionCallbackUsesScopeOfRunContext(t *testing.T) {
+	client := newClient(t)
+	pool := mysql.NewConnectionPool(client)
+	locks := mysql.NewLockFactory(pool)
+	unitOfWorkFactory := mysql.NewUnitOfWorkFactory(pool, nil)
+	factory := mysql.NewLockableUnitOfWorkFactory(locks, unitOfWorkFactory)
+	election := mysql.NewLeaderElection(locks, &testLogger{}, mysql.LeaderElectionConfig{LockName: "leader"})
+
+	ctx, cancel := context.WithCancel(mysql.WithScope(newContext(t)))
+	runScopeID, _ := mysql.ScopeID(ctx)
+	election.Run(ctx, func(jobCtx context.Context) {
+		defer cancel()
+		scopeID, _ := mysql.ScopeID(jobCtx)
+		if scopeID != runScopeID {
+			t.Errorf("expected callback context of scope %d, got %d", runScopeID, scopeID)
+		}
+
+		uow, err := factory.NewLockableUnitOfWork(jobCtx, "job", time.Second)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		insertItem(t, uow.Context(), uow, 1)
+		nested, err := unitOfWorkFactory.UnitOfWork(uow.Context())
+		if err != nil {
+			t.Error(errors.Join(err, uow.Complete(err)))
+			return
+		}
+		insertItem(t, nested.Context(), nested, 2)
+		err = errors.Join(nested.Complete(nil), uow.Complete(nil))
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	assertItems(t, client, 1, 2)
+	assertLocked(t, locks, "leader", false)
+	if election.IsLeader() {
+		t.Fatal("expected leadership to be resigned")
+	}
+}
+
+func TestLeaderElectionCancelsCallbackWhenLockIsLost(t *testing.T) {
+	locks := mysqltest.NewLockFactory()
+	logger := &testLogger{}
+	election := mysql.NewLeaderElection(locks, logger, mysql.LeaderElectionConfig{LockName: "leader"})
+
+	ctx, cancel := context.WithCancel(newContext(t))
+	started := make(chan struct{})
+	cause := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		election.Run(ctx, func(jobCtx context.Context) {
+			close(started)
+			<-jobCtx.Done()
+			cause <- context.Cause(jobCtx)
+		})
+	}()
+
+	<-started
+	if !election.IsLeader() {
+		t.Fatal("expected instance to be leader")
+	}
+	locks.Lose("leader")
+	err := <-cause
+	if !errors.Is(err, mysql.ErrLockLost) {
+		t.Fatalf("expected callback to be cancelled with ErrLockLost, got %v", err)
+	}
+
+	cancel()
+	<-stopped
+	if election.IsLeader() {
+		t.Fatal("expected leadership to be lost")
+	}
+	if errs := logger.Errors(); len(errs) != 1 || !errors.Is(errs[0], mysql.ErrLockLost) {
+		t.Fatalf("expected lost leadership to be logged, got %v", errs)
+	}
+}
@@ -0,0 +1,96 @@
+package mysql
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	applogger "github.com/tss-calculator/go-lib/pkg/application/logger"
+)
+
+type LeakDetectorConfig struct {
+	// Logger reports leaked units of work, they are not reported when it is nil
+	Logger applogger.Logger
+	// GracePeriod is time given to unit of work to complete after its context is done
+	GracePeriod time.Duration
+	// AutoRollback rolls back leaked transaction and returns its connection to pool
+	AutoRollback bool
+}
+
+func newLeakDetector(config LeakDetectorConfig) *leakDetector {
+	return &leakDetector{
+		config: config,
+		open:   make(map[*sharedTransaction]*trackedTransaction),
+	}
+}
+
+type leakDetector struct {
+	config LeakDetectorConfig
+
+	mu   sync.Mutex
+	open map[*sharedTransaction]*trackedTransaction
+}
+
+type trackedTransaction struct {
+	createdAt time.Time
+	stack     []byte
+	done      chan struct{}
+}
+
+func (d *leakDetector) track(stx *sharedTransaction, rollback func(stx *sharedTransaction) error) {
+	tracked := &trackedTransaction{
+		createdAt: time.Now(),
+		stack:     debug.Stack(),
+		done:      make(chan struct{}),
+	}
+
+	d.mu.Lock()
+	d.open[stx] = tracked
+	d.mu.Unlock()
+
+	go d.watch(stx, tracked, rollback)
+}
+
+func (d *leakDetector) untrack(stx *sharedTransaction) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tracked, ok := d.open[stx]
+	if !ok {
+		return
+	}
+	close(tracked.done)
+	delete(d.open, stx)
+}
+
+func (d *leakDetector) watch(stx *sharedTransaction, tracked *trackedTransaction, rollback func(stx *sharedTransaction) error) {
+	select {
+	case <-tracked.done:
+		return
+	case <-stx.ctx.Done():
+	}
+
+	timer := time.NewTimer(d.config.GracePeriod)
+	defer timer.Stop()
+	select {
+	case <-tracked.done:
+		return
+	case <-timer.C:
+	}
+
+	logger := d.config.Logger
+	if logger != nil {
+		logger = logger.WithFields(applogger.Fields{
+			"age":   time.Since(tracked.createdAt).String(),
+			"stack": string(tracked.stack),
+		})
+		logger.Error(ErrUnitOfWorkLeaked, "unit of work is not completed after its context is done")
+	}
+
+	if d.config.AutoRollback {
+		err := rollback(stx)
+		if err != nil && logger != nil {
+			logger.Error(err, "failed rollback leaked unit of work")
+		}
+	}
+}
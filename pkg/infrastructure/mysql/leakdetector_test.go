@@ -0,0 +1,64 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestLeakDetectorReportsUnitOfWorkNotCompletedAfterContextIsDone(t *testing.T) {
+	client := newClient(t)
+	logger := &testLogger{logged: make(chan error, 1)}
+	factory := mysql.NewUnitOfWorkFactoryWithLeakDetector(mysql.NewConnectionPool(client), nil, mysql.LeakDetectorConfig{
+		Logger:      logger,
+		GracePeriod: time.Millisecond * 10,
+	})
+
+	ctx, cancel := context.WithCancel(newContext(t))
+	uow, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	err = <-logger.logged
+	if !errors.Is(err, mysql.ErrUnitOfWorkLeaked) {
+		t.Fatalf("expected ErrUnitOfWorkLeaked, got %v", err)
+	}
+	// leaked unit of work is still owned by caller without AutoRollback
+	err = uow.Complete(errTest)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected error, got %v", err)
+	}
+}
+
+func TestLeakDetectorRollsBackLeakedUnitOfWorkWithoutLogger(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactoryWithLeakDetector(mysql.NewConnectionPool(client), nil, mysql.LeakDetectorConfig{
+		GracePeriod:  time.Millisecond * 10,
+		AutoRollback: true,
+	})
+
+	ctx, cancel := context.WithCancel(newContext(t))
+	leaked, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, leaked, 1)
+	cancel()
+
+	// SQLite runs single write transaction at once, so next unit of work waits for leaked one to be rolled back
+	uow, err := factory.UnitOfWork(newContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, uow.Context(), uow, 2)
+	err = uow.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItems(t, client, 2)
+}
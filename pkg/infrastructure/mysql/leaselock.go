@@ -0,0 +1,347 @@
+package mysql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	// include embed for lease lock schema
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const maxLeaseNameLength = 255
+
+// LeaseLockSchema is DDL of the table used by lease LockFactory
+//
+//go:embed leaselock.sql
+var LeaseLockSchema string
+
+var DefaultLeaseLockConfig = LeaseLockConfig{
+	TTL:           time.Second * 30,
+	RenewInterval: time.Second * 10,
+	PollInterval:  time.Millisecond * 100,
+}
+
+// LeaseLockConfig Owner identifies instance in lease owners, host name is used when it is empty,
+// other zero fields are taken from DefaultLeaseLockConfig. RenewInterval not less than TTL is reduced to third of TTL,
+// so lease is renewed before it expires, and lock is lost once renews fail for TTL less RenewInterval.
+// FencingTokens requires table of FencingTokenSchema
+type LeaseLockConfig struct {
+	Owner         string
+	TTL           time.Duration
+	RenewInterval time.Duration
+	PollInterval  time.Duration
+	FencingTokens bool
+}
+
+// NewLeaseLockFactory creates LockFactory storing locks as rows with expiry renewed by heartbeat, so lock is not
+// released when connection drops. client must not be bound to transaction, e.g. TransactionalClient
+func NewLeaseLockFactory(client ClientContext, config LeaseLockConfig) LockFactory {
+	if config.Owner == "" {
+		config.Owner, _ = os.Hostname()
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultLeaseLockConfig.TTL
+	}
+	if config.RenewInterval <= 0 {
+		config.RenewInterval = DefaultLeaseLockConfig.RenewInterval
+	}
+	if config.RenewInterval >= config.TTL {
+		config.RenewInterval = config.TTL / 3
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultLeaseLockConfig.PollInterval
+	}
+	return &leaseLockFactory{
+		client: client,
+		config: config,
+	}
+}
+
+type leaseLockFactory struct {
+	client    ClientContext
+	config    LeaseLockConfig
+	reentrant reentrantLocks
+}
+
+func (factory *leaseLockFactory) NewLock(ctx context.Context, lockName string, timeout time.Duration) (Lock, error) {
+	return factory.newLock(ctx, []string{lockName}, timeout)
+}
+
+func (factory *leaseLockFactory) TryLock(ctx context.Context, lockName string) (Lock, error) {
+	lock, err := factory.newLock(ctx, []string{lockName}, 0)
+	if errors.Is(err, ErrLockTimeout) {
+		return nil, ErrLockBusy
+	}
+	return lock, err
+}
+
+func (factory *leaseLockFactory) NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	return factory.newLock(ctx, sortedUniqueLockNames(lockNames), timeout)
+}
+
+func (factory *leaseLockFactory) IsLocked(ctx context.Context, lockName string) (bool, error) {
+	holder, err := factory.Holder(ctx, lockName)
+	_, ok := maybe.Just(holder)
+	return ok, err
+}
+
+// Holder returns owner of not expired lease
+func (factory *leaseLockFactory) Holder(ctx context.Context, lockName string) (maybe.Maybe[string], error) {
+	const sqlQuery = "SELECT owner FROM lock_lease WHERE name = ? AND expires_at >= NOW(6)"
+	var owner string
+	err := factory.client.GetContext(withoutScope(ctx), &owner, sqlQuery, shortenLockKey(lockName, maxLeaseNameLength))
+	if errors.Is(err, sql.ErrNoRows) {
+		return maybe.None[string](), nil
+	}
+	if err != nil {
+		return maybe.None[string](), err
+	}
+	return maybe.New(owner), nil
+}
+
+func (factory *leaseLockFactory) newLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	lockKeys := make([]string, 0, len(lockNames))
+	lockNamesByKey := make(map[string]string, len(lockNames))
+	for _, lockName := range lockNames {
+		lockKey := shortenLockKey(lockName, maxLeaseNameLength)
+		lockKeys = append(lockKeys, lockKey)
+		lockNamesByKey[lockKey] = lockName
+	}
+	return factory.reentrant.newLock(ctx, lockKeys, func(lockKeys []string) (keyedLock, error) {
+		lockNames := make([]string, 0, len(lockKeys))
+		for _, lockKey := range lockKeys {
+			lockNames = append(lockNames, lockNamesByKey[lockKey])
+		}
+		lock, err := factory.acquire(ctx, lockNames, lockKeys, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return lock, nil
+	})
+}
+
+// acquire takes leases in order of lock keys and deletes taken leases when any of them is not taken
+func (factory *leaseLockFactory) acquire(ctx context.Context, lockNames, lockKeys []string, timeout time.Duration) (*leaseLock, error) {
+	owner, err := factory.newOwner()
+	if err != nil {
+		return nil, err
+	}
+	lock := &leaseLock{
+		// leases are not taken within transaction of scope, since they must be visible to other instances
+		ctx:      withoutScope(ctx),
+		client:   factory.client,
+		config:   factory.config,
+		lockKeys: lockKeys,
+		owner:    owner,
+		lost:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+
+	// leases expire TTL after they are taken, so the first of them expires TTL after start at latest
+	start := time.Now()
+	deadline := start.Add(timeout)
+	for _, lockKey := range lockKeys {
+		err = lock.take(lockKey, timeout, deadline)
+		if err != nil {
+			return nil, errors.Join(err, lock.deleteLeases())
+		}
+	}
+
+	if factory.config.FencingTokens {
+		lock.fencingTokens, err = issueFencingTokens(lock.ctx, factory.client, lockNames, lockKeys)
+		if err != nil {
+			return nil, errors.Join(err, lock.deleteLeases())
+		}
+	}
+
+	lock.renewedAt = start
+	lock.lockCtx, lock.cancel = context.WithCancelCause(ctx)
+	lock.heartbeatDone.Add(1)
+	go lock.heartbeat()
+	return lock, nil
+}
+
+// newOwner makes owner unique per acquisition, since leases are not bound to connection
+func (factory *leaseLockFactory) newOwner() (string, error) {
+	token := make([]byte, 8)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return shortenLockKey(factory.config.Owner+"/"+hex.EncodeToString(token), maxLeaseNameLength), nil
+}
+
+type leaseLock struct {
+	ctx           context.Context
+	client        ClientContext
+	config        LeaseLockConfig
+	lockKeys      []string
+	owner         string
+	fencingTokens []FencingToken
+	renewedAt     time.Time
+
+	lockCtx       context.Context
+	cancel        context.CancelCauseFunc
+	lost          chan struct{}
+	stop          chan struct{}
+	stopOnce      sync.Once
+	heartbeatDone sync.WaitGroup
+}
+
+// take polls lease until deadline, negative timeout waits until context is done
+func (l *leaseLock) take(lockKey string, timeout time.Duration, deadline time.Time) error {
+	for {
+		taken, err := l.tryTake(lockKey)
+		if err != nil || taken {
+			return err
+		}
+
+		wait := l.config.PollInterval
+		if timeout >= 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return ErrLockTimeout
+			}
+			if remaining < wait {
+				wait = remaining
+			}
+		}
+		err = sleep(l.ctx, wait)
+		if err != nil {
+			return errors.Join(ErrLockTimeout, err)
+		}
+	}
+}
+
+// tryTake inserts lease or takes over expired one, owner is assigned before expiry,
+// so expiry is updated only when lease is taken
+func (l *leaseLock) tryTake(lockKey string) (bool, error) {
+	const sqlQuery = `
+		INSERT INTO lock_lease (name, owner, expires_at)
+		VALUES (?, ?, NOW(6) + INTERVAL ? MICROSECOND)
+		ON DUPLICATE KEY UPDATE
+			owner = IF(expires_at < NOW(6), VALUES(owner), owner),
+			expires_at = IF(owner = VALUES(owner), VALUES(expires_at), expires_at)`
+	_, err := l.client.ExecContext(l.ctx, sqlQuery, lockKey, l.owner, l.config.TTL.Microseconds())
+	if err != nil {
+		return false, err
+	}
+
+	var owner string
+	err = l.client.GetContext(l.ctx, &owner, "SELECT owner FROM lock_lease WHERE name = ?", lockKey)
+	if err != nil {
+		return false, err
+	}
+	return owner == l.owner, nil
+}
+
+func (l *leaseLock) Unlock() error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	l.heartbeatDone.Wait()
+	l.cancel(nil)
+
+	query, args, err := sqlx.In("DELETE FROM lock_lease WHERE name IN (?) AND owner = ?", l.lockKeys, l.owner)
+	if err != nil {
+		return err
+	}
+	// lease is deleted even if context is already cancelled
+	result, err := l.client.ExecContext(detach(l.ctx), query, args...)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted < int64(len(l.lockKeys)) {
+		return ErrLockNotLocked
+	}
+	return nil
+}
+
+func (l *leaseLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *leaseLock) Context() context.Context {
+	return l.lockCtx
+}
+
+func (l *leaseLock) FencingTokens() []FencingToken {
+	return l.fencingTokens
+}
+
+func (l *leaseLock) heldKeys() []string {
+	return l.lockKeys
+}
+
+// heartbeat renews leases, lock is lost when lease is taken over or when renew fails and the lease may expire
+// before the next renew, so the lock is reported lost before another instance can take the lease
+func (l *leaseLock) heartbeat() {
+	defer l.heartbeatDone.Done()
+
+	ticker := time.NewTicker(l.config.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		renewed, err := l.renew()
+		if err == nil && renewed {
+			l.renewedAt = start
+			continue
+		}
+		if err == nil || time.Since(l.renewedAt) >= l.config.TTL-l.config.RenewInterval {
+			close(l.lost)
+			l.cancel(ErrLockLost)
+			return
+		}
+	}
+}
+
+func (l *leaseLock) renew() (bool, error) {
+	ctx, cancel := context.WithTimeout(detach(l.ctx), l.config.RenewInterval)
+	defer cancel()
+
+	query, args, err := sqlx.In(
+		"UPDATE lock_lease SET expires_at = NOW(6) + INTERVAL ? MICROSECOND WHERE name IN (?) AND owner = ?",
+		l.config.TTL.Microseconds(), l.lockKeys, l.owner,
+	)
+	if err != nil {
+		return false, err
+	}
+	_, err = l.client.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	query, args, err = sqlx.In("SELECT COUNT(*) FROM lock_lease WHERE name IN (?) AND owner = ?", l.lockKeys, l.owner)
+	if err != nil {
+		return false, err
+	}
+	var count int
+	err = l.client.GetContext(ctx, &count, query, args...)
+	return count == len(l.lockKeys), err
+}
+
+// deleteLeases releases leases taken before acquisition failed
+func (l *leaseLock) deleteLeases() error {
+	_, err := l.client.ExecContext(detach(l.ctx), "DELETE FROM lock_lease WHERE owner = ?", l.owner)
+	return err
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS lock_lease
+(
+    name       VARCHAR(255) NOT NULL,
+    owner      VARCHAR(255) NOT NULL,
+    expires_at DATETIME(6)  NOT NULL,
+    PRIMARY KEY (name),
+    INDEX lock_lease_owner_idx (owner)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_bin;
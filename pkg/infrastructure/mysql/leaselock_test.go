@@ -0,0 +1,150 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+func TestLeaseLockIsRenewed(t *testing.T) {
+	leases := newLeaseClient()
+	locks := mysql.NewLeaseLockFactory(leases.client, mysql.LeaseLockConfig{
+		TTL:           time.Millisecond * 300,
+		RenewInterval: time.Millisecond * 50,
+	})
+
+	lock, err := locks.NewLock(context.Background(), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-lock.Lost():
+		t.Fatal("expected renewed lock not to be lost")
+	case <-time.After(time.Millisecond * 400):
+	}
+	err = lock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leases.renewals() == 0 {
+		t.Fatal("expected lease to be renewed")
+	}
+}
+
+func TestLeaseLockIsLostBeforeLeaseExpiresWhenRenewFails(t *testing.T) {
+	const ttl = time.Millisecond * 300
+	leases := newLeaseClient()
+	locks := mysql.NewLeaseLockFactory(leases.client, mysql.LeaseLockConfig{
+		TTL:           ttl,
+		RenewInterval: time.Millisecond * 100,
+	})
+
+	start := time.Now()
+	lock, err := locks.NewLock(context.Background(), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leases.failRenew(errTest)
+
+	<-lock.Lost()
+	if elapsed := time.Since(start); elapsed >= ttl {
+		t.Fatalf("expected lock to be lost before lease expires, lost after %s", elapsed)
+	}
+	if !errors.Is(context.Cause(lock.Context()), mysql.ErrLockLost) {
+		t.Fatalf("expected lock context cancelled with ErrLockLost, got %v", context.Cause(lock.Context()))
+	}
+	_ = lock.Unlock()
+}
+
+// leaseClient emulates lock_lease table with single owner per lease
+type leaseClient struct {
+	client *mysqltest.ClientContext
+
+	mu       sync.Mutex
+	owners   map[string]string
+	renewErr error
+	renewed  int
+}
+
+func newLeaseClient() *leaseClient {
+	c := &leaseClient{
+		client: &mysqltest.ClientContext{},
+		owners: make(map[string]string),
+	}
+	c.client.ExecFunc = c.exec
+	c.client.GetFunc = c.get
+	return c
+}
+
+func (c *leaseClient) failRenew(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.renewErr = err
+}
+
+func (c *leaseClient) renewals() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.renewed
+}
+
+func (c *leaseClient) exec(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "INSERT INTO lock_lease"):
+		name, owner := args[0].(string), args[1].(string)
+		if _, ok := c.owners[name]; !ok {
+			c.owners[name] = owner
+		}
+	case strings.HasPrefix(query, "UPDATE lock_lease"):
+		if c.renewErr != nil {
+			return nil, c.renewErr
+		}
+		c.renewed++
+	case strings.HasPrefix(query, "DELETE FROM lock_lease"):
+		owner := args[len(args)-1].(string)
+		deleted := 0
+		for name, o := range c.owners {
+			if o == owner {
+				delete(c.owners, name)
+				deleted++
+			}
+		}
+		return driver.RowsAffected(deleted), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *leaseClient) get(_ context.Context, dest interface{}, query string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT owner"):
+		owner, ok := c.owners[args[0].(string)]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		*dest.(*string) = owner
+	case strings.HasPrefix(query, "SELECT COUNT(*)"):
+		owner := args[len(args)-1].(string)
+		count := 0
+		for _, o := range c.owners {
+			if o == owner {
+				count++
+			}
+		}
+		*dest.(*int) = count
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+)
+
+// NewLocalLockFactory creates LockFactory holding locks in process memory, e.g. for single instance deployments
+// and tests, its locks are never lost
+func NewLocalLockFactory() LockFactory {
+	return &localLockFactory{
+		held:     make(map[string]*localLock),
+		released: make(chan struct{}),
+	}
+}
+
+type localLockFactory struct {
+	reentrant reentrantLocks
+
+	mu           sync.Mutex
+	held         map[string]*localLock
+	released     chan struct{}
+	lastHolderID int64
+}
+
+func (factory *localLockFactory) NewLock(ctx context.Context, lockName string, timeout time.Duration) (Lock, error) {
+	return factory.newLock(ctx, []string{lockName}, timeout)
+}
+
+func (factory *localLockFactory) TryLock(ctx context.Context, lockName string) (Lock, error) {
+	lock, err := factory.newLock(ctx, []string{lockName}, 0)
+	if errors.Is(err, ErrLockTimeout) {
+		return nil, ErrLockBusy
+	}
+	return lock, err
+}
+
+func (factory *localLockFactory) NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	return factory.newLock(ctx, sortedUniqueLockNames(lockNames), timeout)
+}
+
+func (factory *localLockFactory) IsLocked(_ context.Context, lockName string) (bool, error) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	_, ok := factory.held[lockName]
+	return ok, nil
+}
+
+// Holder returns id assigned to lock on acquisition
+func (factory *localLockFactory) Holder(_ context.Context, lockName string) (maybe.Maybe[string], error) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	lock, ok := factory.held[lockName]
+	if !ok {
+		return maybe.None[string](), nil
+	}
+	return maybe.New(strconv.FormatInt(lock.holderID, 10)), nil
+}
+
+func (factory *localLockFactory) newLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	return factory.reentrant.newLock(ctx, lockNames, func(lockKeys []string) (keyedLock, error) {
+		lock, err := factory.acquire(ctx, lockKeys, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return lock, nil
+	})
+}
+
+// acquire takes all lock names at once, so order of lock names does not matter
+func (factory *localLockFactory) acquire(ctx context.Context, lockNames []string, timeout time.Duration) (*localLock, error) {
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		lock, released := factory.tryAcquire(ctx, lockNames)
+		if lock != nil {
+			return lock, nil
+		}
+		select {
+		case <-released:
+		case <-expired:
+			return nil, ErrLockTimeout
+		case <-ctx.Done():
+			return nil, errors.Join(ErrLockTimeout, ctx.Err())
+		}
+	}
+}
+
+// tryAcquire returns channel closed on next release when any of lock names is held
+func (factory *localLockFactory) tryAcquire(ctx context.Context, lockNames []string) (*localLock, <-chan struct{}) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	for _, lockName := range lockNames {
+		if _, ok := factory.held[lockName]; ok {
+			return nil, factory.released
+		}
+	}
+
+	factory.lastHolderID++
+	lock := &localLock{
+		factory:   factory,
+		lockNames: lockNames,
+		holderID:  factory.lastHolderID,
+		lost:      make(chan struct{}),
+	}
+	lock.ctx, lock.cancel = context.WithCancelCause(ctx)
+	for _, lockName := range lockNames {
+		factory.held[lockName] = lock
+	}
+	return lock, nil
+}
+
+func (factory *localLockFactory) release(lock *localLock) error {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	for _, lockName := range lock.lockNames {
+		if factory.held[lockName] != lock {
+			return ErrLockNotLocked
+		}
+	}
+	for _, lockName := range lock.lockNames {
+		delete(factory.held, lockName)
+	}
+	close(factory.released)
+	factory.released = make(chan struct{})
+	return nil
+}
+
+type localLock struct {
+	factory   *localLockFactory
+	lockNames []string
+	holderID  int64
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	lost      chan struct{}
+}
+
+func (l *localLock) Unlock() error {
+	l.cancel(nil)
+	return l.factory.release(l)
+}
+
+func (l *localLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *localLock) Context() context.Context {
+	return l.ctx
+}
+
+// FencingTokens are not issued, since local locks can not be lost
+func (l *localLock) FencingTokens() []FencingToken {
+	return nil
+}
+
+func (l *localLock) heldKeys() []string {
+	return l.lockNames
+}
+
+func sortedUniqueLockNames(lockNames []string) []string {
+	sorted := append([]string(nil), lockNames...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, lockName := range sorted {
+		if i == 0 || lockName != sorted[i-1] {
+			unique = append(unique, lockName)
+		}
+	}
+	return unique
+}
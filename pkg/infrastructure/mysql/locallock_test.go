@@ -0,0 +1,94 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestLocalLockExcludesOtherHolders(t *testing.T) {
+	locks := mysql.NewLocalLockFactory()
+	ctx := newContext(t)
+
+	lock, err := locks.NewLock(ctx, "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLocked(t, locks, "order.42", true)
+	holder, err := locks.Holder(ctx, "order.42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := maybe.Just(holder); !ok {
+		t.Fatal("expected holder of held lock")
+	}
+
+	_, err = locks.TryLock(ctx, "order.42")
+	if !errors.Is(err, mysql.ErrLockBusy) {
+		t.Fatalf("expected ErrLockBusy, got %v", err)
+	}
+	_, err = locks.NewMultiLock(ctx, []string{"order.43", "order.42"}, time.Millisecond*10)
+	if !errors.Is(err, mysql.ErrLockTimeout) {
+		t.Fatalf("expected ErrLockTimeout, got %v", err)
+	}
+	// multi lock takes all lock names at once, so order.43 is not held after timeout
+	assertLocked(t, locks, "order.43", false)
+
+	err = lock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLocked(t, locks, "order.42", false)
+	if context.Cause(lock.Context()) == nil {
+		t.Fatal("expected lock context to be cancelled on unlock")
+	}
+}
+
+func TestLocalLockIsAcquiredOnRelease(t *testing.T) {
+	locks := mysql.NewLocalLockFactory()
+
+	held, err := locks.NewLock(newContext(t), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		// negative timeout waits until lock is released
+		lock, err := locks.NewLock(newContext(t), "order.42", -1)
+		if err == nil {
+			err = lock.Unlock()
+		}
+		acquired <- err
+	}()
+
+	err = held.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-acquired
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalLockCancelJoinsContextError(t *testing.T) {
+	locks := mysql.NewLocalLockFactory()
+
+	held, err := locks.NewLock(newContext(t), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Unlock()
+
+	ctx, cancel := context.WithCancel(newContext(t))
+	cancel()
+	_, err = locks.NewLock(ctx, "order.42", time.Second)
+	if !errors.Is(err, mysql.ErrLockTimeout) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrLockTimeout joined with context error, got %v", err)
+	}
+}
@@ -4,88 +4,517 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+)
+
+const (
+	DefaultLockHeartbeatInterval = time.Second * 10
+
+	lockPollInterval = time.Millisecond * 50
+	killQueryTimeout = time.Second * 5
 )
 
 var (
 	ErrLockTimeout   = errors.New("lock timed out")
 	ErrLockNotLocked = errors.New("lock not locked")
 	ErrLockNotFound  = errors.New("lock not found")
+	ErrLockLost      = errors.New("lock lost")
+	ErrLockBusy      = errors.New("lock busy")
 )
 
 type LockFactory interface {
 	NewLock(ctx context.Context, lockName string, timeout time.Duration) (Lock, error)
+	// TryLock fails with ErrLockBusy without waiting when lock is held by another session
+	TryLock(ctx context.Context, lockName string) (Lock, error)
+	// NewMultiLock acquires locks in sorted order within single session, so concurrent callers do not deadlock,
+	// timeout limits acquisition of all locks
+	NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error)
+	IsLocked(ctx context.Context, lockName string) (bool, error)
+	// Holder identifies holder of lock, e.g. connection id of session holding GET_LOCK or owner of lease
+	Holder(ctx context.Context, lockName string) (maybe.Maybe[string], error)
 }
 
+// Lock is reentrant within scope of context, acquisition of lock already held within the scope
+// does not query server and the lock is released by the last Unlock. Lock opened with context without scope
+// starts new scope carried by Context, so locks and units of work reenter it only with Context of the lock
+// or with context of WithScope, e.g. attached by HandlerWithScope
 type Lock interface {
 	Unlock() error
+	// Lost is closed when heartbeat finds lock released by server, e.g. after lock connection dropped
+	Lost() <-chan struct{}
+	// Context is cancelled with ErrLockLost cause when lock is lost and when lock is unlocked
+	Context() context.Context
+	// FencingTokens are issued on acquisition per lock name, when LockFactory is configured to issue them
+	FencingTokens() []FencingToken
+}
+
+// LockFactoryConfig zero HeartbeatInterval disables heartbeat, so Lost is never closed and lock connections
+// are not kept alive. FencingTokens requires table of FencingTokenSchema
+type LockFactoryConfig struct {
+	HeartbeatInterval time.Duration
+	FencingTokens     bool
 }
 
 func NewLockFactory(connectionPool ConnectionPool) LockFactory {
-	return &lockFactory{connectionPool: connectionPool}
+	return NewLockFactoryWithConfig(connectionPool, LockFactoryConfig{
+		HeartbeatInterval: DefaultLockHeartbeatInterval,
+	})
+}
+
+func NewLockFactoryWithConfig(connectionPool ConnectionPool, config LockFactoryConfig) LockFactory {
+	factory := &lockFactory{
+		connectionPool: connectionPool,
+		config:         config,
+		stats:          libraryStatsOf(connectionPool),
+	}
+	if config.HeartbeatInterval > 0 {
+		factory.heartbeat = newLockHeartbeat(connectionPool, config.HeartbeatInterval)
+	}
+	return factory
 }
 
 type lockFactory struct {
 	connectionPool ConnectionPool
+	config         LockFactoryConfig
+	stats          *libraryStats
+	heartbeat      *lockHeartbeat
+
+	reentrant reentrantLocks
+
+	mu             sync.Mutex
+	database       string
+	databaseLoaded bool
 }
 
 func (factory *lockFactory) NewLock(ctx context.Context, lockName string, timeout time.Duration) (Lock, error) {
+	return factory.newLock(ctx, []string{lockName}, timeout)
+}
+
+func (factory *lockFactory) TryLock(ctx context.Context, lockName string) (Lock, error) {
+	lock, err := factory.newLock(ctx, []string{lockName}, 0)
+	if errors.Is(err, ErrLockTimeout) {
+		return nil, ErrLockBusy
+	}
+	return lock, err
+}
+
+func (factory *lockFactory) NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	return factory.newLock(ctx, sortedUniqueLockNames(lockNames), timeout)
+}
+
+func (factory *lockFactory) IsLocked(ctx context.Context, lockName string) (locked bool, err error) {
+	conn, err := factory.connectionPool.TransactionalConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	lockKeys, err := factory.lockKeys(ctx, conn, []string{lockName})
+	if err != nil {
+		return false, err
+	}
+
+	const sqlQuery = "SELECT IS_FREE_LOCK(?)"
+	var free bool
+	err = conn.GetContext(ctx, &free, sqlQuery, lockKeys[0])
+	return !free, err
+}
+
+func (factory *lockFactory) Holder(ctx context.Context, lockName string) (holder maybe.Maybe[string], err error) {
+	conn, err := factory.connectionPool.TransactionalConnection(ctx)
+	if err != nil {
+		return maybe.None[string](), err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	lockKeys, err := factory.lockKeys(ctx, conn, []string{lockName})
+	if err != nil {
+		return maybe.None[string](), err
+	}
+
+	connectionID, err := usedLockConnectionID(ctx, conn, lockKeys[0])
+	if err != nil || !connectionID.Valid {
+		return maybe.None[string](), err
+	}
+	return maybe.New(strconv.FormatInt(connectionID.Int64, 10)), nil
+}
+
+func (factory *lockFactory) newLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	// scope is joined before connection is taken, so units of work opened with Context of the lock share its session
+	ctx, _ = joinScope(ctx)
 	conn, err := factory.connectionPool.TransactionalConnection(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	lockKeys, err := factory.lockKeys(ctx, conn, lockNames)
+	if err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+
+	lockNamesByKey := make(map[string]string, len(lockKeys))
+	for i, lockKey := range lockKeys {
+		lockNamesByKey[lockKey] = lockNames[i]
+	}
+
+	acquired := false
+	lock, err := factory.reentrant.newLock(ctx, lockKeys, func(lockKeys []string) (keyedLock, error) {
+		acquired = true
+		lockNames := make([]string, 0, len(lockKeys))
+		for _, lockKey := range lockKeys {
+			lockNames = append(lockNames, lockNamesByKey[lockKey])
+		}
+		l, err := factory.acquire(ctx, conn, lockNames, lockKeys, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return l, nil
+	})
+	if acquired {
+		return lock, err
+	}
+	// connection is not used, when all locks are already held within scope
+	closeErr := conn.Close()
+	if closeErr != nil && lock != nil {
+		closeErr = errors.Join(closeErr, lock.Unlock())
+		return nil, closeErr
+	}
+	return lock, errors.Join(err, closeErr)
+}
+
+// acquire closes conn when locks are not acquired
+func (factory *lockFactory) acquire(
+	ctx context.Context,
+	conn TransactionalConnection,
+	lockNames, lockKeys []string,
+	timeout time.Duration,
+) (*lockImpl, error) {
 	lock := lockImpl{
-		ctx:      ctx,
-		lockName: lockName,
-		timeout:  timeout,
-		conn:     conn,
+		ctx:            ctx,
+		lockNames:      lockNames,
+		lockKeys:       lockKeys,
+		timeout:        timeout,
+		conn:           conn,
+		connectionPool: factory.connectionPool,
+		stats:          factory.stats,
+		lost:           make(chan struct{}),
 	}
 
-	err = lock.Lock()
+	err := lock.Lock()
 	if err != nil {
 		err = errors.Join(err, conn.Close())
 		return nil, err
 	}
+	factory.stats.heldLocks.Add(int64(len(lockKeys)))
 
-	return &lock, err
+	if factory.config.FencingTokens {
+		err = lock.issueFencingTokens()
+		if err != nil {
+			return nil, errors.Join(err, lock.release())
+		}
+	}
+
+	lock.lockCtx, lock.cancel = context.WithCancelCause(ctx)
+	if factory.heartbeat != nil {
+		lock.heartbeat = factory.heartbeat
+		lock.heartbeat.add(&lock)
+	}
+
+	return &lock, nil
+}
+
+// lockKeys scopes lock names to database as server-side locks are shared by all databases of server
+func (factory *lockFactory) lockKeys(ctx context.Context, client ClientContext, lockNames []string) ([]string, error) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	if !factory.databaseLoaded {
+		var database sql.NullString
+		err := client.GetContext(ctx, &database, "SELECT DATABASE()")
+		if err != nil {
+			return nil, err
+		}
+		factory.database, factory.databaseLoaded = database.String, true
+	}
+
+	lockKeys := make([]string, 0, len(lockNames))
+	for _, lockName := range lockNames {
+		lockKeys = append(lockKeys, lockKey(lockName, factory.database))
+	}
+	return lockKeys, nil
 }
 
 type lockImpl struct {
-	ctx      context.Context
-	lockName string
-	timeout  time.Duration
-	conn     TransactionalConnection
+	ctx            context.Context
+	lockNames      []string
+	lockKeys       []string
+	fencingTokens  []FencingToken
+	timeout        time.Duration
+	conn           TransactionalConnection
+	connectionID   int64
+	connectionPool ConnectionPool
+	stats          *libraryStats
+
+	lockCtx   context.Context
+	cancel    context.CancelCauseFunc
+	lost      chan struct{}
+	lostOnce  sync.Once
+	heartbeat *lockHeartbeat
+	// mu serializes pings of lock connection with release of lock
+	mu       sync.Mutex
+	released atomic.Bool
 }
 
+// Lock releases already acquired locks when any of lock names is not acquired
 func (l *lockImpl) Lock() error {
-	const sqlQuery = "SELECT GET_LOCK(SUBSTRING(CONCAT(?, '.', DATABASE()), 1, 64), ?)"
-	var result int
-	err := l.conn.GetContext(l.ctx, &result, sqlQuery, l.lockName, int(l.timeout.Seconds()))
-	if result == 0 && err == nil {
+	err := l.conn.GetContext(l.ctx, &l.connectionID, "SELECT CONNECTION_ID()")
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(l.timeout)
+	for i, lockKey := range l.lockKeys {
+		timeout := l.timeout
+		if i > 0 && l.timeout >= 0 {
+			timeout = time.Until(deadline)
+			if timeout < 0 {
+				timeout = 0
+			}
+		}
+		err = l.lock(lockKey, timeout)
+		if err != nil {
+			for j := i - 1; j >= 0; j-- {
+				err = errors.Join(err, l.unlock(l.lockKeys[j]))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// lock waits whole seconds of timeout within GET_LOCK and polls for the rest,
+// since GET_LOCK timeout is truncated to seconds
+func (l *lockImpl) lock(lockKey string, timeout time.Duration) error {
+	deadline, limitedByContext := l.ctx.Deadline()
+	limitedByContext = limitedByContext && (timeout < 0 || time.Until(deadline) < timeout)
+	if limitedByContext {
+		timeout = time.Until(deadline)
+		if timeout < 0 {
+			timeout = 0
+		}
+	}
+
+	stop := l.killQueryOnCancel()
+
+	wait := timeout
+	if timeout > 0 {
+		wait = timeout.Truncate(time.Second)
+	}
+	start := time.Now()
+	acquired, err := l.getLock(lockKey, wait)
+	for err == nil && !acquired && timeout > 0 {
+		remaining := timeout - time.Since(start)
+		if remaining <= 0 {
+			break
+		}
+		if remaining > lockPollInterval {
+			remaining = lockPollInterval
+		}
+		if sleep(l.ctx, remaining) != nil {
+			break
+		}
+		acquired, err = l.getLock(lockKey, 0)
+	}
+	// pending KILL QUERY is awaited before RELEASE_LOCK, so it can not interrupt releasing lock
+	killErr := stop()
+
+	if l.ctx.Err() != nil {
+		// GET_LOCK is interrupted with KILL QUERY, but lock may be acquired before
+		if acquired {
+			err = errors.Join(err, l.unlock(lockKey))
+		}
+		return errors.Join(ErrLockTimeout, l.ctx.Err(), err, killErr)
+	}
+	if err != nil {
+		return err
+	}
+	if !acquired && limitedByContext {
+		return errors.Join(ErrLockTimeout, context.DeadlineExceeded)
+	}
+	if !acquired {
 		return ErrLockTimeout
 	}
-	return err
+	return nil
 }
 
-func (l *lockImpl) Unlock() (err error) {
+// getLock waits infinitely for negative timeout
+func (l *lockImpl) getLock(lockKey string, timeout time.Duration) (bool, error) {
+	seconds := int(timeout.Seconds())
+	if timeout < 0 {
+		seconds = -1
+	}
+	// query is not cancelled by driver, since driver closes connection on cancel, KILL QUERY interrupts it instead
+	const sqlQuery = "SELECT GET_LOCK(?, ?)"
+	var result sql.NullInt32
+	err := l.conn.GetContext(detach(l.ctx), &result, sqlQuery, lockKey, seconds)
+	return result.Valid && result.Int32 == 1, err
+}
+
+// killQueryOnCancel interrupts GET_LOCK from separate connection when context is done,
+// stop waits for pending KILL QUERY, so it can not interrupt following queries
+func (l *lockImpl) killQueryOnCancel() (stop func() error) {
+	if l.ctx.Done() == nil {
+		return func() error {
+			return nil
+		}
+	}
+
+	done := make(chan struct{})
+	killed := make(chan error, 1)
+	go func() {
+		select {
+		case <-done:
+			killed <- nil
+		case <-l.ctx.Done():
+			killed <- l.killQuery()
+		}
+	}()
+	return func() error {
+		close(done)
+		return <-killed
+	}
+}
+
+func (l *lockImpl) killQuery() (err error) {
+	// context with hidden scope gets connection other than lock connection
+	ctx, cancel := context.WithTimeout(withoutScope(context.Background()), killQueryTimeout)
+	defer cancel()
+
+	conn, err := l.connectionPool.TransactionalConnection(ctx)
+	if err != nil {
+		return err
+	}
 	defer func() {
-		freeErr := l.conn.Close()
-		err = errors.Join(err, freeErr)
+		err = errors.Join(err, conn.Close())
 	}()
 
-	const sqlQuery = "SELECT RELEASE_LOCK(SUBSTRING(CONCAT(?, '.', DATABASE()), 1, 64))"
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", l.connectionID))
+	return err
+}
+
+func (l *lockImpl) Unlock() error {
+	l.mu.Lock()
+	l.released.Store(true)
+	l.mu.Unlock()
+	if l.heartbeat != nil {
+		l.heartbeat.remove(l)
+	}
+	l.cancel(nil)
+	return l.release()
+}
+
+// release unlocks lock keys in reverse order and frees lock connection
+func (l *lockImpl) release() error {
+	var err error
+	for i := len(l.lockKeys) - 1; i >= 0; i-- {
+		err = errors.Join(err, l.unlock(l.lockKeys[i]))
+	}
+	l.stats.heldLocks.Add(-int64(len(l.lockKeys)))
+	return errors.Join(err, l.conn.Close())
+}
+
+func (l *lockImpl) unlock(lockKey string) error {
+	const sqlQuery = "SELECT RELEASE_LOCK(?)"
 	var result sql.NullInt32
-	err = l.conn.GetContext(l.ctx, &result, sqlQuery, l.lockName)
+	// lock is released even if context is already cancelled
+	err := l.conn.GetContext(detach(l.ctx), &result, sqlQuery, lockKey)
 	if err == nil {
 		if !result.Valid {
-			err = ErrLockNotFound
-			return err
+			return ErrLockNotFound
 		}
 		if result.Int32 == 0 {
-			err = ErrLockNotLocked
-			return err
+			return ErrLockNotLocked
 		}
 	}
 	return err
 }
+
+func (l *lockImpl) FencingTokens() []FencingToken {
+	return l.fencingTokens
+}
+
+// issueFencingTokens uses connection out of scope, since connection of lock may be bound to transaction
+func (l *lockImpl) issueFencingTokens() (err error) {
+	ctx := withoutScope(l.ctx)
+	conn, err := l.connectionPool.TransactionalConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	l.fencingTokens, err = issueFencingTokens(ctx, conn, l.lockNames, l.lockKeys)
+	return err
+}
+
+func (l *lockImpl) heldKeys() []string {
+	return l.lockKeys
+}
+
+func (l *lockImpl) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *lockImpl) Context() context.Context {
+	return l.lockCtx
+}
+
+// ping keeps lock connection from being dropped by server as idle, it is called with l.mu held.
+// Connection shared with units of work is pinged only while the lock uses it alone, since units of work
+// may read rows from it
+func (l *lockImpl) ping() {
+	defer l.mu.Unlock()
+	if l.released.Load() {
+		return
+	}
+
+	ping := func() error {
+		var result int
+		// query is not cancelled, since driver closes connection on cancel and server releases lock with it
+		return l.conn.GetContext(detach(l.ctx), &result, "SELECT 1")
+	}
+	// failed ping is not handled, since dropped connection is found by IS_USED_LOCK check
+	if conn, ok := l.conn.(*sharedConnection); ok {
+		_, _ = conn.runExclusive(ping)
+		return
+	}
+	_ = ping()
+}
+
+func (l *lockImpl) lose() {
+	if l.released.Load() {
+		return
+	}
+	l.lostOnce.Do(func() {
+		close(l.lost)
+		l.cancel(ErrLockLost)
+	})
+}
+
+func usedLockConnectionID(ctx context.Context, client ClientContext, lockKey string) (sql.NullInt64, error) {
+	const sqlQuery = "SELECT IS_USED_LOCK(?)"
+	var connectionID sql.NullInt64
+	err := client.GetContext(ctx, &connectionID, sqlQuery, lockKey)
+	return connectionID, err
+}
@@ -0,0 +1,285 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+func TestLockHonorsSubSecondTimeout(t *testing.T) {
+	locks := mysql.NewLockFactory(mysql.NewConnectionPool(newClient(t)))
+
+	held, err := locks.NewLock(newContext(t), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Unlock()
+
+	start := time.Now()
+	_, err = locks.NewLock(newContext(t), "order.42", time.Millisecond*300)
+	elapsed := time.Since(start)
+	if !errors.Is(err, mysql.ErrLockTimeout) {
+		t.Fatalf("expected ErrLockTimeout, got %v", err)
+	}
+	if elapsed < time.Millisecond*250 || elapsed > time.Second {
+		t.Fatalf("expected lock to wait about 300ms, waited %s", elapsed)
+	}
+}
+
+func TestLockIsAcquiredWithinSubSecondTimeout(t *testing.T) {
+	locks := mysql.NewLockFactory(mysql.NewConnectionPool(newClient(t)))
+
+	held, err := locks.NewLock(newContext(t), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(time.Millisecond*100, func() {
+		_ = held.Unlock()
+	})
+
+	lock, err := locks.NewLock(newContext(t), "order.42", time.Millisecond*500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockHonorsContextDeadline(t *testing.T) {
+	locks := mysql.NewLockFactory(mysql.NewConnectionPool(newClient(t)))
+
+	held, err := locks.NewLock(newContext(t), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Unlock()
+
+	ctx, cancel := context.WithTimeout(newContext(t), time.Millisecond*200)
+	defer cancel()
+	start := time.Now()
+	_, err = locks.NewLock(ctx, "order.42", time.Second*10)
+	elapsed := time.Since(start)
+	if !errors.Is(err, mysql.ErrLockTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrLockTimeout with context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed > time.Second*2 {
+		t.Fatalf("expected lock to wait until context deadline, waited %s", elapsed)
+	}
+}
+
+func TestLockCancelInterruptsServerSideWait(t *testing.T) {
+	locks := mysql.NewLockFactory(mysql.NewConnectionPool(newClient(t)))
+
+	held, err := locks.NewLock(newContext(t), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(newContext(t))
+	time.AfterFunc(time.Millisecond*100, cancel)
+	start := time.Now()
+	_, err = locks.NewLock(ctx, "order.42", -1)
+	elapsed := time.Since(start)
+	if !errors.Is(err, mysql.ErrLockTimeout) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrLockTimeout with context.Canceled, got %v", err)
+	}
+	if elapsed > time.Second*2 {
+		t.Fatalf("expected cancel to interrupt GET_LOCK, waited %s", elapsed)
+	}
+
+	err = held.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked, err := locks.IsLocked(newContext(t), "order.42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked {
+		t.Fatal("expected interrupted acquisition not to hold lock")
+	}
+}
+
+func TestMultiLockReleasesAcquiredLocksOnTimeout(t *testing.T) {
+	locks := mysql.NewLockFactory(mysql.NewConnectionPool(newClient(t)))
+
+	held, err := locks.NewLock(newContext(t), "b", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Unlock()
+
+	_, err = locks.NewMultiLock(newContext(t), []string{"b", "a"}, time.Millisecond*200)
+	if !errors.Is(err, mysql.ErrLockTimeout) {
+		t.Fatalf("expected ErrLockTimeout, got %v", err)
+	}
+	locked, err := locks.IsLocked(newContext(t), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked {
+		t.Fatal("expected lock acquired before timeout to be released")
+	}
+}
+
+func TestLockIsReentrantWithinLockableUnitOfWork(t *testing.T) {
+	pool := mysql.NewConnectionPool(newClient(t))
+	locks := mysql.NewLockFactory(pool)
+	factory := mysql.NewLockableUnitOfWorkFactory(locks, mysql.NewUnitOfWorkFactory(pool, nil))
+	ctx := mysql.WithScope(newContext(t))
+
+	uow, err := factory.NewLockableUnitOfWork(ctx, "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// helper locks the same name with the same context
+	lock, err := locks.NewLock(ctx, "order.42", time.Millisecond*100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := factory.NewLockableUnitOfWork(uow.Context(), "order.42", time.Millisecond*100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = errors.Join(nested.Complete(nil), lock.Unlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLocked(t, locks, "order.42", true)
+
+	err = uow.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLocked(t, locks, "order.42", false)
+}
+
+func TestLockIsNotReentrantAcrossContexts(t *testing.T) {
+	locks := mysql.NewLockFactory(mysql.NewConnectionPool(newClient(t)))
+
+	held, err := locks.NewLock(newContext(t), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Unlock()
+
+	_, err = locks.TryLock(newContext(t), "order.42")
+	if !errors.Is(err, mysql.ErrLockBusy) {
+		t.Fatalf("expected ErrLockBusy, got %v", err)
+	}
+}
+
+func TestLockTimeoutErrorReportsDeadlock(t *testing.T) {
+	locks := &waitingLockFactory{
+		LockFactory: mysql.NewLockFactory(mysql.NewConnectionPool(newClient(t))),
+		waiting:     make(chan string, 1),
+	}
+	// units of work are faked, since SQLite runs single write transaction at once
+	factory := mysql.NewLockableUnitOfWorkFactory(locks, mysqltest.NewUnitOfWorkFactory())
+
+	first, err := factory.NewLockableUnitOfWork(newContext(t), "a", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-locks.waiting
+	second, err := factory.NewLockableUnitOfWork(newContext(t), "b", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-locks.waiting
+
+	firstErr := make(chan error, 1)
+	go func() {
+		uow, err := factory.NewLockableUnitOfWork(first.Context(), "b", time.Second*5)
+		if err == nil {
+			err = uow.Complete(nil)
+		}
+		firstErr <- err
+	}()
+	// wait of the first unit of work is tracked before it requests lock
+	<-locks.waiting
+
+	_, err = factory.NewLockableUnitOfWork(second.Context(), "a", time.Millisecond*300)
+	<-locks.waiting
+	var timeoutErr *mysql.LockTimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, mysql.ErrLockTimeout) {
+		t.Fatalf("expected LockTimeoutError, got %v", err)
+	}
+	if len(timeoutErr.Deadlock) != 2 {
+		t.Fatalf("expected deadlock of two units of work, got %v", timeoutErr.Deadlock)
+	}
+
+	err = errors.Join(second.Complete(nil), <-firstErr, first.Complete(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertLocked(t *testing.T, locks mysql.LockFactory, lockName string, expected bool) {
+	t.Helper()
+	locked, err := locks.IsLocked(newContext(t), lockName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked != expected {
+		t.Fatalf("expected lock %q locked to be %v", lockName, expected)
+	}
+}
+
+// waitingLockFactory reports lock names requested from LockFactory
+type waitingLockFactory struct {
+	mysql.LockFactory
+	waiting chan string
+}
+
+func (factory *waitingLockFactory) NewLock(ctx context.Context, lockName string, timeout time.Duration) (mysql.Lock, error) {
+	factory.waiting <- lockName
+	return factory.LockFactory.NewLock(ctx, lockName, timeout)
+}
+
+func TestHeartbeatReportsLockReleasedOnServer(t *testing.T) {
+	pool := mysql.NewConnectionPool(newClient(t))
+	locks := mysql.NewLockFactoryWithConfig(pool, mysql.LockFactoryConfig{HeartbeatInterval: time.Millisecond * 10})
+	factory := mysql.NewUnitOfWorkFactory(pool, nil)
+
+	lock, err := locks.NewLock(mysql.WithScope(newContext(t)), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unit of work of lock context shares lock session, so it can drop the lock behind lock's back
+	uow, err := factory.UnitOfWork(lock.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = uow.ClientContext().ExecContext(uow.Context(), "SELECT RELEASE_ALL_LOCKS()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = uow.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected heartbeat to report lost lock")
+	}
+	if cause := context.Cause(lock.Context()); !errors.Is(cause, mysql.ErrLockLost) {
+		t.Fatalf("expected lock context to be cancelled with ErrLockLost, got %v", cause)
+	}
+	// lock does not exist on server any more
+	err = lock.Unlock()
+	if !errors.Is(err, mysql.ErrLockNotFound) {
+		t.Fatalf("expected ErrLockNotFound, got %v", err)
+	}
+}
@@ -4,16 +4,22 @@ import (
 	"context"
 	"errors"
 	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
 )
 
 const DefaultLockTimeout = time.Second * 5
 
 type LockableUnitOfWorkFactory interface {
+	// NewLockableUnitOfWork returns *LockTimeoutError describing lock holder and waits among units of work
+	// of the factory, when lock is not acquired in time
 	NewLockableUnitOfWork(ctx context.Context, lockName string, timeout time.Duration) (LockableUnitOfWork, error)
 }
 
 type LockableUnitOfWork interface {
 	UnitOfWork
+	// FencingToken of the lock, when LockFactory issues fencing tokens
+	FencingToken() maybe.Maybe[FencingToken]
 }
 
 func NewLockableUnitOfWorkFactory(
@@ -23,20 +29,26 @@ func NewLockableUnitOfWorkFactory(
 	return &lockableUnitOfWorkFactory{
 		lockFactory:       lockFactory,
 		unitOfWorkFactory: unitOfWorkFactory,
+		tracker:           newLockTracker(),
 	}
 }
 
 type lockableUnitOfWorkFactory struct {
 	lockFactory       LockFactory
 	unitOfWorkFactory UnitOfWorkFactory
+	tracker           *lockTracker
 }
 
 func (factory *lockableUnitOfWorkFactory) NewLockableUnitOfWork(ctx context.Context, lockName string, timeout time.Duration) (LockableUnitOfWork, error) {
+	// context without scope or with hidden scope starts new scope, so its lock waits are tracked
+	// and nested units of work join it
+	ctx, scope := joinScope(ctx)
+
 	var lock Lock
 
 	if lockName != "" {
 		var err error
-		lock, err = factory.lockFactory.NewLock(ctx, lockName, timeout)
+		lock, err = factory.lock(ctx, scope.id, lockName, timeout)
 		if err != nil {
 			return nil, err
 		}
@@ -46,25 +58,67 @@ func (factory *lockableUnitOfWorkFactory) NewLockableUnitOfWork(ctx context.Cont
 	if err != nil {
 		if lock != nil {
 			err = errors.Join(err, lock.Unlock())
+			factory.tracker.release(scope.id, lockName)
 		}
 		return nil, err
 	}
 
 	return &lockableUnitOfWork{
 		lock:       lock,
+		lockName:   lockName,
+		scopeID:    scope.id,
+		tracker:    factory.tracker,
 		UnitOfWork: unitOfWork,
 	}, nil
 }
 
+// lock describes lock holder and waits among scopes of factory, when lock is timed out
+func (factory *lockableUnitOfWorkFactory) lock(ctx context.Context, scopeID uint64, lockName string, timeout time.Duration) (Lock, error) {
+	factory.tracker.wait(scopeID, lockName)
+	lock, err := factory.lockFactory.NewLock(ctx, lockName, timeout)
+	if err == nil {
+		factory.tracker.acquire(scopeID, lockName)
+		return lock, nil
+	}
+	if !errors.Is(err, ErrLockTimeout) {
+		factory.tracker.stopWaiting(scopeID)
+		return nil, err
+	}
+
+	timeoutErr := factory.tracker.timeoutError(scopeID, lockName, err)
+	factory.tracker.stopWaiting(scopeID)
+
+	diagnosticsCtx, cancel := context.WithTimeout(detach(ctx), lockDiagnosticsTimeout)
+	defer cancel()
+	// holder is best effort, timeout is reported even if holder is not described
+	timeoutErr.Holder, _ = describeLockHolder(diagnosticsCtx, factory.lockFactory, lockName)
+	return nil, timeoutErr
+}
+
 type lockableUnitOfWork struct {
 	UnitOfWork
-	lock Lock
+	lock     Lock
+	lockName string
+	scopeID  uint64
+	tracker  *lockTracker
 }
 
 func (u *lockableUnitOfWork) Complete(err error) error {
 	returnErr := u.UnitOfWork.Complete(err)
 	if u.lock != nil {
 		returnErr = errors.Join(returnErr, u.lock.Unlock())
+		u.tracker.release(u.scopeID, u.lockName)
 	}
 	return returnErr
 }
+
+func (u *lockableUnitOfWork) FencingToken() maybe.Maybe[FencingToken] {
+	if u.lock == nil {
+		return maybe.None[FencingToken]()
+	}
+	tokens := u.lock.FencingTokens()
+	if len(tokens) == 0 {
+		return maybe.None[FencingToken]()
+	}
+	return maybe.New(tokens[0])
+}
@@ -0,0 +1,40 @@
+package mysql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestLockableUnitOfWorkStartsScopeForContextWithHiddenScope(t *testing.T) {
+	client := newClient(t)
+	pool := mysql.NewConnectionPool(client)
+	locks := mysql.NewLockFactory(pool)
+	unitOfWorkFactory := mysql.NewUnitOfWorkFactory(pool, nil)
+	factory := mysql.NewLockableUnitOfWorkFactory(locks, unitOfWorkFactory)
+
+	uow, err := factory.NewLockableUnitOfWork(mysql.WithoutScope(newContext(t)), "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, uow.Context(), uow, 1)
+	assertLocked(t, locks, "order.42", true)
+
+	nested, err := unitOfWorkFactory.UnitOfWork(uow.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, nested.Context(), nested, 2)
+	err = nested.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = uow.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLocked(t, locks, "order.42", false)
+	assertItems(t, client, 1, 2)
+}
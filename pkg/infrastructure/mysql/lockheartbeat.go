@@ -0,0 +1,119 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// lockHeartbeat keeps lock connections of factory alive and checks its locks with IS_USED_LOCK
+// over single connection at interval, it runs while any lock is held
+type lockHeartbeat struct {
+	connectionPool ConnectionPool
+	interval       time.Duration
+
+	mu    sync.Mutex
+	locks map[*lockImpl]struct{}
+	stop  chan struct{}
+}
+
+func newLockHeartbeat(connectionPool ConnectionPool, interval time.Duration) *lockHeartbeat {
+	return &lockHeartbeat{
+		connectionPool: connectionPool,
+		interval:       interval,
+		locks:          make(map[*lockImpl]struct{}),
+	}
+}
+
+func (h *lockHeartbeat) add(l *lockImpl) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.locks[l] = struct{}{}
+	if h.stop == nil {
+		h.stop = make(chan struct{})
+		go h.run(h.stop)
+	}
+}
+
+func (h *lockHeartbeat) remove(l *lockImpl) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.locks, l)
+	if len(h.locks) == 0 && h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+	}
+}
+
+func (h *lockHeartbeat) run(stop chan struct{}) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		h.beat()
+	}
+}
+
+// beat pings lock connections concurrently, so connection busy with long query does not delay others,
+// lock connection which is still pinged since previous beat is skipped
+func (h *lockHeartbeat) beat() {
+	h.mu.Lock()
+	locks := make([]*lockImpl, 0, len(h.locks))
+	for l := range h.locks {
+		locks = append(locks, l)
+	}
+	h.mu.Unlock()
+
+	for _, l := range locks {
+		if l.mu.TryLock() {
+			go l.ping()
+		}
+	}
+
+	lost, err := h.lostLocks(locks)
+	// failed check is retried, since it tells nothing about lock connections
+	if err != nil {
+		return
+	}
+	for _, l := range lost {
+		l.lose()
+		h.remove(l)
+	}
+}
+
+// lostLocks checks locks from separate connection, since lock connections may be busy with caller queries
+func (h *lockHeartbeat) lostLocks(locks []*lockImpl) (lost []*lockImpl, err error) {
+	// context with hidden scope gets connection other than lock connections
+	ctx, cancel := context.WithTimeout(withoutScope(context.Background()), h.interval)
+	defer cancel()
+
+	conn, err := h.connectionPool.TransactionalConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	for _, l := range locks {
+		for _, lockKey := range l.lockKeys {
+			holder, err := usedLockConnectionID(ctx, conn, lockKey)
+			if err != nil {
+				return nil, err
+			}
+			if !holder.Valid || holder.Int64 != l.connectionID {
+				lost = append(lost, l)
+				break
+			}
+		}
+	}
+	return lost, nil
+}
@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxLockKeyLength is limit of MySQL named lock length in characters
+	maxLockKeyLength  = 64
+	lockKeyHashLength = 32
+	lockNameSeparator = ":"
+)
+
+// LockName composes namespaced lock names, e.g. NewLockName("calculator").Entity("tariff", 42) is "calculator:tariff:42"
+type LockName struct {
+	parts []string
+}
+
+func NewLockName(namespace string) LockName {
+	return LockName{parts: []string{escapeLockNamePart(namespace)}}
+}
+
+func (n LockName) Entity(entityType string, id interface{}) LockName {
+	parts := make([]string, 0, len(n.parts)+2)
+	parts = append(parts, n.parts...)
+	parts = append(parts, escapeLockNamePart(entityType), escapeLockNamePart(fmt.Sprint(id)))
+	return LockName{parts: parts}
+}
+
+func (n LockName) String() string {
+	return strings.Join(n.parts, lockNameSeparator)
+}
+
+// escapeLockNamePart keeps parts containing separator from colliding with other names
+func escapeLockNamePart(part string) string {
+	return strings.NewReplacer(`\`, `\\`, lockNameSeparator, `\`+lockNameSeparator).Replace(part)
+}
+
+// lockKey scopes lock name to database as GET_LOCK locks are shared by all databases of server
+func lockKey(lockName, database string) string {
+	return shortenLockKey(lockName+"."+database, maxLockKeyLength)
+}
+
+// shortenLockKey keeps short keys readable, keys exceeding limit are replaced with prefix and hash of whole key
+func shortenLockKey(key string, maxLength int) string {
+	if utf8.RuneCountInString(key) <= maxLength {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])[:lockKeyHashLength]
+	prefix := []rune(key)[:maxLength-lockKeyHashLength-1]
+	return string(prefix) + "#" + hash
+}
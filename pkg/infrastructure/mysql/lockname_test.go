@@ -0,0 +1,53 @@
+package mysql_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestLockNameEscapesSeparator(t *testing.T) {
+	name := mysql.NewLockName("calculator").Entity("tariff", 42).String()
+	if name != "calculator:tariff:42" {
+		t.Fatalf("unexpected lock name %s", name)
+	}
+
+	first := mysql.NewLockName("a:b").Entity("c", "d").String()
+	second := mysql.NewLockName("a").Entity("b:c", "d").String()
+	if first == second {
+		t.Fatalf("expected different lock names, got %s", first)
+	}
+	if first != `a\:b:c:d` {
+		t.Fatalf("unexpected lock name %s", first)
+	}
+}
+
+func TestLockKeyKeepsShortNames(t *testing.T) {
+	key := mysql.LockKey("calculator:tariff:42", "app")
+	if key != "calculator:tariff:42.app" {
+		t.Fatalf("unexpected lock key %s", key)
+	}
+}
+
+func TestLockKeyShortensLongNames(t *testing.T) {
+	long := strings.Repeat("ж", 70)
+	key := mysql.LockKey(long, "app")
+	if utf8.RuneCountInString(key) != 64 {
+		t.Fatalf("expected lock key of 64 characters, got %d: %s", utf8.RuneCountInString(key), key)
+	}
+	if !strings.HasPrefix(key, strings.Repeat("ж", 31)+"#") {
+		t.Fatalf("expected readable prefix, got %s", key)
+	}
+
+	if key != mysql.LockKey(long, "app") {
+		t.Fatal("expected lock key to be stable")
+	}
+	if key == mysql.LockKey(long, "other") {
+		t.Fatal("expected lock keys of different databases to differ")
+	}
+	if key == mysql.LockKey(long+"ж", "app") {
+		t.Fatal("expected lock keys of names sharing prefix to differ")
+	}
+}
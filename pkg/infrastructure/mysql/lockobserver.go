@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+)
+
+type LockObserver interface {
+	ObserveLockAcquire(ctx context.Context, lockName string, wait time.Duration, err error)
+	ObserveLockRelease(lockName string, err error)
+}
+
+func InstrumentLockFactory(factory LockFactory, observer LockObserver) LockFactory {
+	return &instrumentedLockFactory{
+		factory:  factory,
+		observer: observer,
+	}
+}
+
+type instrumentedLockFactory struct {
+	factory  LockFactory
+	observer LockObserver
+}
+
+func (factory *instrumentedLockFactory) NewLock(ctx context.Context, lockName string, timeout time.Duration) (Lock, error) {
+	return factory.observe(ctx, lockName, func() (Lock, error) {
+		return factory.factory.NewLock(ctx, lockName, timeout)
+	})
+}
+
+func (factory *instrumentedLockFactory) TryLock(ctx context.Context, lockName string) (Lock, error) {
+	return factory.observe(ctx, lockName, func() (Lock, error) {
+		return factory.factory.TryLock(ctx, lockName)
+	})
+}
+
+// NewMultiLock is observed as single lock named after all lock names
+func (factory *instrumentedLockFactory) NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	return factory.observe(ctx, strings.Join(lockNames, ","), func() (Lock, error) {
+		return factory.factory.NewMultiLock(ctx, lockNames, timeout)
+	})
+}
+
+func (factory *instrumentedLockFactory) IsLocked(ctx context.Context, lockName string) (bool, error) {
+	return factory.factory.IsLocked(ctx, lockName)
+}
+
+func (factory *instrumentedLockFactory) Holder(ctx context.Context, lockName string) (maybe.Maybe[string], error) {
+	return factory.factory.Holder(ctx, lockName)
+}
+
+func (factory *instrumentedLockFactory) describeHolder(ctx context.Context, lockName string) (maybe.Maybe[LockHolder], error) {
+	return describeLockHolder(ctx, factory.factory, lockName)
+}
+
+func (factory *instrumentedLockFactory) observe(ctx context.Context, lockName string, acquire func() (Lock, error)) (Lock, error) {
+	start := time.Now()
+	lock, err := acquire()
+	factory.observer.ObserveLockAcquire(ctx, lockName, time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedLock{
+		Lock:     lock,
+		lockName: lockName,
+		observer: factory.observer,
+	}, nil
+}
+
+type instrumentedLock struct {
+	Lock
+	lockName string
+	observer LockObserver
+}
+
+func (l *instrumentedLock) Unlock() error {
+	err := l.Lock.Unlock()
+	l.observer.ObserveLockRelease(l.lockName, err)
+	return err
+}
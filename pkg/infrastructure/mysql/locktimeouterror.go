@@ -0,0 +1,270 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+)
+
+const lockDiagnosticsTimeout = time.Second * 5
+
+// LockTimeoutError is returned by LockableUnitOfWorkFactory when lock is not acquired in time,
+// scope ids identify contexts of lockable units of work of the factory like ScopeID does
+type LockTimeoutError struct {
+	LockName string
+	ScopeID  uint64
+	// HeldLocks are lock names held by scope in order of acquisition
+	HeldLocks []string
+	Holder    maybe.Maybe[LockHolder]
+	// WaitGraph lists scopes waiting for locks held by other scopes of the factory
+	WaitGraph []LockWait
+	// Deadlock is cycle of wait graph starting from scope, it is empty when there is no cycle
+	Deadlock []LockWait
+
+	err error
+}
+
+// LockHolder process fields are read from processlist for GET_LOCK locks, they are empty when the process
+// is not visible, e.g. without PROCESS privilege, or when lock is not session lock
+type LockHolder struct {
+	ID      string
+	User    string
+	Host    string
+	Command string
+	Time    time.Duration
+	State   string
+	Info    string
+}
+
+type LockWait struct {
+	ScopeID       uint64
+	LockName      string
+	HolderScopeID uint64
+}
+
+func (e *LockTimeoutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %q in scope %d", e.err, e.LockName, e.ScopeID)
+	if holder, ok := maybe.Just(e.Holder); ok {
+		fmt.Fprintf(&b, ", held by %s", holder.ID)
+		if holder.User != "" {
+			fmt.Fprintf(&b, " (%s@%s, %s for %s", holder.User, holder.Host, holder.Command, holder.Time)
+			if holder.State != "" {
+				fmt.Fprintf(&b, ", %s", holder.State)
+			}
+			if holder.Info != "" {
+				fmt.Fprintf(&b, ": %s", holder.Info)
+			}
+			b.WriteString(")")
+		}
+	}
+	if len(e.HeldLocks) > 0 {
+		fmt.Fprintf(&b, ", scope holds %q", e.HeldLocks)
+	}
+	if len(e.Deadlock) > 0 {
+		b.WriteString(", deadlock:")
+		for _, wait := range e.Deadlock {
+			fmt.Fprintf(&b, " scope %d waits %q held by scope %d;", wait.ScopeID, wait.LockName, wait.HolderScopeID)
+		}
+		return strings.TrimSuffix(b.String(), ";")
+	}
+	return b.String()
+}
+
+func (e *LockTimeoutError) Is(target error) bool {
+	return target == ErrLockTimeout
+}
+
+func (e *LockTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// lockTracker tracks lock names held and awaited by scopes
+type lockTracker struct {
+	mu      sync.Mutex
+	holders map[string]uint64
+	held    map[uint64][]string
+	waiting map[uint64]string
+}
+
+func newLockTracker() *lockTracker {
+	return &lockTracker{
+		holders: make(map[string]uint64),
+		held:    make(map[uint64][]string),
+		waiting: make(map[uint64]string),
+	}
+}
+
+func (t *lockTracker) wait(scopeID uint64, lockName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.waiting[scopeID] = lockName
+}
+
+func (t *lockTracker) stopWaiting(scopeID uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.waiting, scopeID)
+}
+
+// acquire is counted per acquisition, since lock may be acquired again within scope
+func (t *lockTracker) acquire(scopeID uint64, lockName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.waiting, scopeID)
+	t.holders[lockName] = scopeID
+	t.held[scopeID] = append(t.held[scopeID], lockName)
+}
+
+func (t *lockTracker) release(scopeID uint64, lockName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	held := t.held[scopeID]
+	for i := len(held) - 1; i >= 0; i-- {
+		if held[i] == lockName {
+			held = append(held[:i], held[i+1:]...)
+			break
+		}
+	}
+	if len(held) == 0 {
+		delete(t.held, scopeID)
+	} else {
+		t.held[scopeID] = held
+	}
+	for _, name := range held {
+		if name == lockName {
+			return
+		}
+	}
+	if t.holders[lockName] == scopeID {
+		delete(t.holders, lockName)
+	}
+}
+
+// timeoutError is built before scope stops waiting, so scope is in wait graph
+func (t *lockTracker) timeoutError(scopeID uint64, lockName string, err error) *LockTimeoutError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	waitGraph := t.waitGraph()
+	return &LockTimeoutError{
+		LockName:  lockName,
+		ScopeID:   scopeID,
+		HeldLocks: append([]string(nil), t.held[scopeID]...),
+		WaitGraph: waitGraph,
+		Deadlock:  deadlock(waitGraph, scopeID),
+		err:       err,
+	}
+}
+
+func (t *lockTracker) waitGraph() []LockWait {
+	var waitGraph []LockWait
+	for scopeID, lockName := range t.waiting {
+		holderScopeID, ok := t.holders[lockName]
+		if !ok || holderScopeID == scopeID {
+			continue
+		}
+		waitGraph = append(waitGraph, LockWait{
+			ScopeID:       scopeID,
+			LockName:      lockName,
+			HolderScopeID: holderScopeID,
+		})
+	}
+	sort.Slice(waitGraph, func(i, j int) bool {
+		return waitGraph[i].ScopeID < waitGraph[j].ScopeID
+	})
+	return waitGraph
+}
+
+// deadlock follows waits from scope, scope waits for single lock at most, so path is unique
+func deadlock(waitGraph []LockWait, scopeID uint64) []LockWait {
+	waits := make(map[uint64]LockWait, len(waitGraph))
+	for _, wait := range waitGraph {
+		waits[wait.ScopeID] = wait
+	}
+
+	var path []LockWait
+	visited := make(map[uint64]bool)
+	for current := scopeID; !visited[current]; {
+		visited[current] = true
+		wait, ok := waits[current]
+		if !ok {
+			return nil
+		}
+		path = append(path, wait)
+		current = wait.HolderScopeID
+		if current == scopeID {
+			return path
+		}
+	}
+	return nil
+}
+
+// lockHolderDescriber is implemented by LockFactory able to describe holder beyond LockFactory.Holder
+type lockHolderDescriber interface {
+	describeHolder(ctx context.Context, lockName string) (maybe.Maybe[LockHolder], error)
+}
+
+func describeLockHolder(ctx context.Context, factory LockFactory, lockName string) (maybe.Maybe[LockHolder], error) {
+	if describer, ok := factory.(lockHolderDescriber); ok {
+		return describer.describeHolder(ctx, lockName)
+	}
+	holder, err := factory.Holder(ctx, lockName)
+	if id, ok := maybe.Just(holder); ok && err == nil {
+		return maybe.New(LockHolder{ID: id}), nil
+	}
+	return maybe.None[LockHolder](), err
+}
+
+func (factory *lockFactory) describeHolder(ctx context.Context, lockName string) (holder maybe.Maybe[LockHolder], err error) {
+	conn, err := factory.connectionPool.TransactionalConnection(ctx)
+	if err != nil {
+		return maybe.None[LockHolder](), err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	lockKeys, err := factory.lockKeys(ctx, conn, []string{lockName})
+	if err != nil {
+		return maybe.None[LockHolder](), err
+	}
+	connectionID, err := usedLockConnectionID(ctx, conn, lockKeys[0])
+	if err != nil || !connectionID.Valid {
+		return maybe.None[LockHolder](), err
+	}
+
+	const sqlQuery = `
+		SELECT USER, HOST, COMMAND, TIME, STATE, INFO
+		FROM information_schema.PROCESSLIST
+		WHERE ID = ?`
+	var process struct {
+		User    string         `db:"USER"`
+		Host    string         `db:"HOST"`
+		Command string         `db:"COMMAND"`
+		Time    int64          `db:"TIME"`
+		State   sql.NullString `db:"STATE"`
+		Info    sql.NullString `db:"INFO"`
+	}
+	result := LockHolder{ID: fmt.Sprint(connectionID.Int64)}
+	// holder is described by connection id alone, when process is not visible
+	if conn.GetContext(ctx, &process, sqlQuery, connectionID.Int64) != nil {
+		return maybe.New(result), nil
+	}
+	result.User = process.User
+	result.Host = process.Host
+	result.Command = process.Command
+	result.Time = time.Duration(process.Time) * time.Second
+	result.State = process.State.String
+	result.Info = process.Info.String
+	return maybe.New(result), nil
+}
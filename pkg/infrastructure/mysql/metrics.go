@@ -0,0 +1,287 @@
+package mysql
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	unitOfWorkResultCommitted  = "committed"
+	unitOfWorkResultRolledBack = "rolled_back"
+	unitOfWorkResultFailed     = "failed"
+
+	lockResultAcquired  = "acquired"
+	lockResultReleased  = "released"
+	lockResultTimeout   = "timeout"
+	lockResultBusy      = "busy"
+	lockResultNotFound  = "not_found"
+	lockResultNotLocked = "not_locked"
+	lockResultError     = "error"
+)
+
+var defaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects metrics of units of work, locks and queries and serves them in Prometheus text format
+type Metrics interface {
+	QueryObserver
+	LockObserver
+	// UnitOfWorkCompleteCallback counts completed outermost units of work and calls next callback if it is set,
+	// nested units of work are not counted, since they complete savepoints
+	UnitOfWorkCompleteCallback(next UnitOfWorkCompleteCallback) UnitOfWorkCompleteCallback
+	Handler() http.Handler
+}
+
+func NewMetrics() Metrics {
+	return &metrics{
+		unitsOfWork: newCounterVec(
+			"mysql_unit_of_work_total",
+			"Completed units of work by result.",
+			"result",
+		),
+		lockAcquisitions: newCounterVec(
+			"mysql_lock_acquisitions_total",
+			"Named lock acquisitions by result.",
+			"result",
+		),
+		lockReleases: newCounterVec(
+			"mysql_lock_releases_total",
+			"Named lock releases by result.",
+			"result",
+		),
+		lockWait: newHistogramVec(
+			"mysql_lock_wait_seconds",
+			"Time spent waiting for named lock by result.",
+			"result",
+			defaultDurationBuckets,
+		),
+		queryDuration: newHistogramVec(
+			"mysql_query_duration_seconds",
+			"Query latency by operation.",
+			"operation",
+			defaultDurationBuckets,
+		),
+		queryErrors: newCounterVec(
+			"mysql_query_errors_total",
+			"Failed queries by operation.",
+			"operation",
+		),
+	}
+}
+
+type metrics struct {
+	unitsOfWork      *counterVec
+	lockAcquisitions *counterVec
+	lockReleases     *counterVec
+	lockWait         *histogramVec
+	queryDuration    *histogramVec
+	queryErrors      *counterVec
+}
+
+func (m *metrics) ObserveQuery(_ context.Context, event QueryEvent) {
+	m.queryDuration.observe(event.Operation, event.Duration.Seconds())
+	if event.Err != nil {
+		m.queryErrors.inc(event.Operation)
+	}
+}
+
+func (m *metrics) ObserveLockAcquire(_ context.Context, _ string, wait time.Duration, err error) {
+	var result string
+	switch {
+	case err == nil:
+		result = lockResultAcquired
+	case errors.Is(err, ErrLockTimeout):
+		result = lockResultTimeout
+	case errors.Is(err, ErrLockBusy):
+		result = lockResultBusy
+	default:
+		result = lockResultError
+	}
+	m.lockAcquisitions.inc(result)
+	m.lockWait.observe(result, wait.Seconds())
+}
+
+func (m *metrics) ObserveLockRelease(_ string, err error) {
+	var result string
+	switch {
+	case err == nil:
+		result = lockResultReleased
+	case errors.Is(err, ErrLockNotFound):
+		result = lockResultNotFound
+	case errors.Is(err, ErrLockNotLocked):
+		result = lockResultNotLocked
+	default:
+		result = lockResultError
+	}
+	m.lockReleases.inc(result)
+}
+
+func (m *metrics) UnitOfWorkCompleteCallback(next UnitOfWorkCompleteCallback) UnitOfWorkCompleteCallback {
+	return func(ctx context.Context, err error) {
+		var committed *committedError
+		switch {
+		case isNestedUnitOfWork(ctx):
+		case err == nil, errors.As(err, &committed):
+			m.unitsOfWork.inc(unitOfWorkResultCommitted)
+		case errors.Is(err, ErrTransactionFailed):
+			m.unitsOfWork.inc(unitOfWorkResultFailed)
+		default:
+			m.unitsOfWork.inc(unitOfWorkResultRolledBack)
+		}
+		if next != nil {
+			next(ctx, err)
+		}
+	}
+}
+
+// isNestedUnitOfWork tells whether completed unit of work is nested,
+// transaction is removed from scope when the outermost unit of work completes
+func isNestedUnitOfWork(ctx context.Context) bool {
+	_, ok := activeTransaction(ctx)
+	return ok
+}
+
+func (m *metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.write(writer)
+	})
+}
+
+func (m *metrics) write(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	m.unitsOfWork.write(buf)
+	m.lockAcquisitions.write(buf)
+	m.lockReleases.write(buf)
+	m.lockWait.write(buf)
+	m.queryDuration.write(buf)
+	m.queryErrors.write(buf)
+	return buf.Flush()
+}
+
+func newCounterVec(name, help, label string) *counterVec {
+	return &counterVec{
+		name:   name,
+		help:   help,
+		label:  label,
+		values: make(map[string]uint64),
+	}
+}
+
+type counterVec struct {
+	name  string
+	help  string
+	label string
+
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+func (c *counterVec) inc(labelValue string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[labelValue]++
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, labelValue := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s{%s} %d\n", c.name, formatLabel(c.label, labelValue), c.values[labelValue])
+	}
+}
+
+func newHistogramVec(name, help, label string, buckets []float64) *histogramVec {
+	return &histogramVec{
+		name:    name,
+		help:    help,
+		label:   label,
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+}
+
+type histogramVec struct {
+	name    string
+	help    string
+	label   string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogramVec) observe(labelValue string, value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[labelValue]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[labelValue] = s
+	}
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, labelValue := range sortedKeys(h.series) {
+		s := h.series[labelValue]
+		label := formatLabel(h.label, labelValue)
+		for i, upperBound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", h.name, label, formatFloat(upperBound), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", h.name, label, s.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", h.name, label, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", h.name, label, s.count)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabel(name, value string) string {
+	return name + `="` + labelValueReplacer.Replace(value) + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+package mysql_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestMetricsCountOutermostUnitsOfWork(t *testing.T) {
+	client := newClient(t)
+	metrics := mysql.NewMetrics()
+	var completed int
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), metrics.UnitOfWorkCompleteCallback(func(context.Context, error) {
+		completed++
+	}))
+	ctx := newContext(t)
+
+	outer, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = nested.Complete(errTest)
+	if err == nil {
+		t.Fatal("expected nested error")
+	}
+	err = outer.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed.OnCommit(func(context.Context) error {
+		return errTest
+	})
+	err = failed.Complete(nil)
+	if err == nil {
+		t.Fatal("expected hook error")
+	}
+
+	rolledBack, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rolledBack.Complete(errTest)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if completed != 4 {
+		t.Fatalf("expected next callback to be called for every unit of work, got %d calls", completed)
+	}
+	exposition := scrapeMetrics(t, metrics)
+	assertMetric(t, exposition, `mysql_unit_of_work_total{result="committed"} 2`)
+	assertMetric(t, exposition, `mysql_unit_of_work_total{result="rolled_back"} 1`)
+}
+
+func TestMetricsExposeLocksAndQueries(t *testing.T) {
+	metrics := mysql.NewMetrics()
+	ctx := newContext(t)
+
+	metrics.ObserveLockAcquire(ctx, "lock", time.Millisecond*20, nil)
+	metrics.ObserveLockAcquire(ctx, "lock", time.Second, mysql.ErrLockTimeout)
+	metrics.ObserveLockRelease("lock", nil)
+	metrics.ObserveLockRelease("lock", mysql.ErrLockNotLocked)
+	metrics.ObserveQuery(ctx, mysql.QueryEvent{Operation: mysql.QueryOperationExec, Duration: time.Millisecond * 3})
+	metrics.ObserveQuery(ctx, mysql.QueryEvent{Operation: mysql.QueryOperationExec, Duration: time.Millisecond * 30, Err: errTest})
+
+	exposition := scrapeMetrics(t, metrics)
+	for _, line := range []string{
+		"# TYPE mysql_lock_acquisitions_total counter",
+		`mysql_lock_acquisitions_total{result="acquired"} 1`,
+		`mysql_lock_acquisitions_total{result="timeout"} 1`,
+		`mysql_lock_releases_total{result="released"} 1`,
+		`mysql_lock_releases_total{result="not_locked"} 1`,
+		"# TYPE mysql_lock_wait_seconds histogram",
+		`mysql_lock_wait_seconds_bucket{result="acquired",le="0.01"} 0`,
+		`mysql_lock_wait_seconds_bucket{result="acquired",le="0.025"} 1`,
+		`mysql_lock_wait_seconds_count{result="timeout"} 1`,
+		`mysql_query_duration_seconds_bucket{operation="exec",le="0.005"} 1`,
+		`mysql_query_duration_seconds_bucket{operation="exec",le="+Inf"} 2`,
+		`mysql_query_duration_seconds_sum{operation="exec"} 0.033`,
+		`mysql_query_errors_total{operation="exec"} 1`,
+	} {
+		assertMetric(t, exposition, line)
+	}
+}
+
+func scrapeMetrics(t *testing.T, metrics mysql.Metrics) string {
+	t.Helper()
+	response := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", response.Code)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func assertMetric(t *testing.T, exposition, line string) {
+	t.Helper()
+	for _, l := range strings.Split(exposition, "\n") {
+		if l == line {
+			return
+		}
+	}
+	t.Fatalf("expected line %s in exposition:\n%s", line, exposition)
+}
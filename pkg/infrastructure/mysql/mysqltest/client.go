@@ -0,0 +1,78 @@
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+type Query struct {
+	Query string
+	Args  []interface{}
+}
+
+// ClientContext records queries and delegates them to funcs, unset funcs succeed without touching dest,
+// QueryFunc and QueryRowFunc must be set to use QueryContext and QueryRowContext
+type ClientContext struct {
+	QueryFunc    func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowFunc func(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecFunc     func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	SelectFunc   func(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	GetFunc      func(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+
+	mu      sync.Mutex
+	queries []Query
+}
+
+func (c *ClientContext) Queries() []Query {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Query(nil), c.queries...)
+}
+
+func (c *ClientContext) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	c.record(query, args)
+	if c.QueryFunc == nil {
+		panic("mysqltest: ClientContext.QueryFunc is not set")
+	}
+	return c.QueryFunc(ctx, query, args...)
+}
+
+func (c *ClientContext) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	c.record(query, args)
+	if c.QueryRowFunc == nil {
+		panic("mysqltest: ClientContext.QueryRowFunc is not set")
+	}
+	return c.QueryRowFunc(ctx, query, args...)
+}
+
+func (c *ClientContext) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	c.record(query, args)
+	if c.ExecFunc == nil {
+		return driver.RowsAffected(0), nil
+	}
+	return c.ExecFunc(ctx, query, args...)
+}
+
+func (c *ClientContext) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	c.record(query, args)
+	if c.SelectFunc == nil {
+		return nil
+	}
+	return c.SelectFunc(ctx, dest, query, args...)
+}
+
+func (c *ClientContext) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	c.record(query, args)
+	if c.GetFunc == nil {
+		return nil
+	}
+	return c.GetFunc(ctx, dest, query, args...)
+}
+
+func (c *ClientContext) record(query string, args []interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, Query{Query: query, Args: args})
+}
@@ -0,0 +1,94 @@
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+// ConnectionPool fakes mysql.ConnectionPool, all connections and transactions use Client
+type ConnectionPool struct {
+	Client *ClientContext
+	// Err is returned instead of connection when set
+	Err error
+
+	mu        sync.Mutex
+	opened    int
+	closed    int
+	commits   int
+	rollbacks int
+}
+
+func NewConnectionPool() *ConnectionPool {
+	return &ConnectionPool{Client: &ClientContext{}}
+}
+
+func (p *ConnectionPool) TransactionalConnection(context.Context) (mysql.TransactionalConnection, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	p.opened++
+	return &connection{ClientContext: p.Client, pool: p}, nil
+}
+
+func (p *ConnectionPool) AssertAllReleased(t testing.TB) {
+	t.Helper()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.opened != p.closed {
+		t.Errorf("mysqltest: %d of %d connections are not released", p.opened-p.closed, p.opened)
+	}
+}
+
+func (p *ConnectionPool) Commits() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.commits
+}
+
+func (p *ConnectionPool) Rollbacks() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rollbacks
+}
+
+func (p *ConnectionPool) count(counter *int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*counter++
+}
+
+type connection struct {
+	*ClientContext
+	pool *ConnectionPool
+}
+
+func (c *connection) BeginTransaction(context.Context, *sql.TxOptions) (mysql.Transaction, error) {
+	return &transaction{ClientContext: c.ClientContext, pool: c.pool}, nil
+}
+
+func (c *connection) Close() error {
+	c.pool.count(&c.pool.closed)
+	return nil
+}
+
+type transaction struct {
+	*ClientContext
+	pool *ConnectionPool
+}
+
+func (tx *transaction) Commit() error {
+	tx.pool.count(&tx.pool.commits)
+	return nil
+}
+
+func (tx *transaction) Rollback() error {
+	tx.pool.count(&tx.pool.rollbacks)
+	return nil
+}
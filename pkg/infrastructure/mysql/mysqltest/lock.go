@@ -0,0 +1,273 @@
+package mysqltest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+// LockFactory fakes mysql.LockFactory with in-memory locks, acquisition of held lock waits up to timeout
+// and fails with mysql.ErrLockTimeout. Locks are reentrant within context scope like locks of mysql.LockFactory,
+// so acquisition within scope holding the lock is not counted in Acquisitions
+type LockFactory struct {
+	reentrant mysql.LockFactory
+
+	mu           sync.Mutex
+	held         map[string]*lock
+	failures     map[string]error
+	acquisitions []string
+	lastHolderID int64
+	lastTokens   map[string]int64
+}
+
+func NewLockFactory() *LockFactory {
+	f := &LockFactory{
+		held:       make(map[string]*lock),
+		failures:   make(map[string]error),
+		lastTokens: make(map[string]int64),
+	}
+	f.reentrant = mysql.NewReentrantLockFactory(lockBackend{factory: f})
+	return f
+}
+
+func (f *LockFactory) NewLock(ctx context.Context, lockName string, timeout time.Duration) (mysql.Lock, error) {
+	return f.reentrant.NewLock(ctx, lockName, timeout)
+}
+
+func (f *LockFactory) TryLock(ctx context.Context, lockName string) (mysql.Lock, error) {
+	return f.reentrant.TryLock(ctx, lockName)
+}
+
+// NewMultiLock acquires all lock names at once, Acquisitions lists them sorted
+func (f *LockFactory) NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (mysql.Lock, error) {
+	return f.reentrant.NewMultiLock(ctx, lockNames, timeout)
+}
+
+func (f *LockFactory) IsLocked(_ context.Context, lockName string) (bool, error) {
+	return f.IsHeld(lockName), nil
+}
+
+// Holder returns id assigned to lock on acquisition instead of connection id
+func (f *LockFactory) Holder(_ context.Context, lockName string) (maybe.Maybe[string], error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l, ok := f.held[lockName]
+	if !ok {
+		return maybe.None[string](), nil
+	}
+	return maybe.New(strconv.FormatInt(l.holderID, 10)), nil
+}
+
+// FailNext makes next acquisition of lockName fail with err, e.g. mysql.ErrLockTimeout
+func (f *LockFactory) FailNext(lockName string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[lockName] = err
+}
+
+// Hold acquires lockName on behalf of another process to simulate contention
+func (f *LockFactory) Hold(lockName string) (release func()) {
+	l, err := f.newLock(context.Background(), []string{lockName}, -1)
+	if err != nil {
+		panic(err)
+	}
+	return func() {
+		_ = l.Unlock()
+	}
+}
+
+// Lose simulates lock released by server, Lost of lock holding lockName is closed and its context is cancelled
+func (f *LockFactory) Lose(lockName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l, ok := f.held[lockName]
+	if !ok {
+		return
+	}
+	close(l.lost)
+	l.cancel(mysql.ErrLockLost)
+	f.release(l)
+}
+
+func (f *LockFactory) IsHeld(lockName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.held[lockName]
+	return ok
+}
+
+// Acquisitions returns names of successfully acquired locks in order of acquisition
+func (f *LockFactory) Acquisitions() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acquisitions...)
+}
+
+func (f *LockFactory) AssertHeld(t testing.TB, lockName string) {
+	t.Helper()
+	if !f.IsHeld(lockName) {
+		t.Errorf("mysqltest: expected lock %q to be held", lockName)
+	}
+}
+
+func (f *LockFactory) AssertNotHeld(t testing.TB, lockName string) {
+	t.Helper()
+	if f.IsHeld(lockName) {
+		t.Errorf("mysqltest: expected lock %q to be released", lockName)
+	}
+}
+
+func (f *LockFactory) AssertAllReleased(t testing.TB) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for lockName := range f.held {
+		t.Errorf("mysqltest: lock %q is not released", lockName)
+	}
+}
+
+// lockBackend acquires locks of factory without reentrancy
+type lockBackend struct {
+	factory *LockFactory
+}
+
+func (b lockBackend) NewLock(ctx context.Context, lockName string, timeout time.Duration) (mysql.Lock, error) {
+	return b.factory.newLock(ctx, []string{lockName}, timeout)
+}
+
+func (b lockBackend) TryLock(ctx context.Context, lockName string) (mysql.Lock, error) {
+	l, err := b.factory.newLock(ctx, []string{lockName}, 0)
+	if errors.Is(err, mysql.ErrLockTimeout) {
+		return nil, mysql.ErrLockBusy
+	}
+	return l, err
+}
+
+// NewMultiLock gets lock names sorted and unique from reentrant factory
+func (b lockBackend) NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (mysql.Lock, error) {
+	return b.factory.newLock(ctx, lockNames, timeout)
+}
+
+func (b lockBackend) IsLocked(ctx context.Context, lockName string) (bool, error) {
+	return b.factory.IsLocked(ctx, lockName)
+}
+
+func (b lockBackend) Holder(ctx context.Context, lockName string) (maybe.Maybe[string], error) {
+	return b.factory.Holder(ctx, lockName)
+}
+
+// newLock waits infinitely for negative timeout and fails with mysql.ErrLockTimeout joined with error of done
+// context like mysql.LockFactory
+func (f *LockFactory) newLock(ctx context.Context, lockNames []string, timeout time.Duration) (mysql.Lock, error) {
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		l, released, err := f.tryLock(ctx, lockNames)
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			return l, nil
+		}
+
+		select {
+		case <-released:
+		case <-expired:
+			return nil, mysql.ErrLockTimeout
+		case <-ctx.Done():
+			return nil, errors.Join(mysql.ErrLockTimeout, ctx.Err())
+		}
+	}
+}
+
+// tryLock returns channel closed on release when any of lock names is held by someone else
+func (f *LockFactory) tryLock(ctx context.Context, lockNames []string) (*lock, <-chan struct{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, lockName := range lockNames {
+		if err, ok := f.failures[lockName]; ok {
+			delete(f.failures, lockName)
+			return nil, nil, err
+		}
+		if held, ok := f.held[lockName]; ok {
+			return nil, held.released, nil
+		}
+	}
+	f.lastHolderID++
+	l := &lock{
+		factory:   f,
+		lockNames: lockNames,
+		holderID:  f.lastHolderID,
+		released:  make(chan struct{}),
+		lost:      make(chan struct{}),
+	}
+	l.ctx, l.cancel = context.WithCancelCause(ctx)
+	for _, lockName := range lockNames {
+		f.held[lockName] = l
+		f.lastTokens[lockName]++
+		l.tokens = append(l.tokens, mysql.FencingToken{LockName: lockName, Value: f.lastTokens[lockName]})
+	}
+	f.acquisitions = append(f.acquisitions, lockNames...)
+	return l, nil, nil
+}
+
+func (f *LockFactory) unlock(l *lock) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l.cancel(nil)
+	if f.held[l.lockNames[0]] != l {
+		return mysql.ErrLockNotLocked
+	}
+	f.release(l)
+	return nil
+}
+
+func (f *LockFactory) release(l *lock) {
+	close(l.released)
+	for _, lockName := range l.lockNames {
+		delete(f.held, lockName)
+	}
+}
+
+type lock struct {
+	factory   *LockFactory
+	lockNames []string
+	holderID  int64
+	released  chan struct{}
+	lost      chan struct{}
+	tokens    []mysql.FencingToken
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+}
+
+func (l *lock) Unlock() error {
+	return l.factory.unlock(l)
+}
+
+func (l *lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *lock) Context() context.Context {
+	return l.ctx
+}
+
+// FencingTokens increase with every acquisition of lock name by the factory
+func (l *lock) FencingTokens() []mysql.FencingToken {
+	return l.tokens
+}
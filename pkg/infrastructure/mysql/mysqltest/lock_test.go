@@ -0,0 +1,82 @@
+package mysqltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+func TestLockFailsWithLockTimeoutOnCancel(t *testing.T) {
+	locks := mysqltest.NewLockFactory()
+	release := locks.Hold("order.42")
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := locks.NewLock(ctx, "order.42", -1)
+	if !errors.Is(err, mysql.ErrLockTimeout) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrLockTimeout with context.Canceled, got %v", err)
+	}
+}
+
+func TestLockWaitsInfinitelyForNegativeTimeout(t *testing.T) {
+	locks := mysqltest.NewLockFactory()
+	release := locks.Hold("order.42")
+
+	acquired := make(chan error, 1)
+	go func() {
+		lock, err := locks.NewLock(context.Background(), "order.42", -1)
+		if err == nil {
+			err = lock.Unlock()
+		}
+		acquired <- err
+	}()
+	select {
+	case err := <-acquired:
+		t.Fatalf("expected lock to wait for release, got %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	release()
+	err := <-acquired
+	if err != nil {
+		t.Fatal(err)
+	}
+	locks.AssertAllReleased(t)
+}
+
+func TestLockIsReentrantWithinScope(t *testing.T) {
+	locks := mysqltest.NewLockFactory()
+	ctx := mysql.WithScope(context.Background())
+
+	lock, err := locks.NewLock(ctx, "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reentered, err := locks.TryLock(ctx, "order.42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = locks.TryLock(context.Background(), "order.42")
+	if !errors.Is(err, mysql.ErrLockBusy) {
+		t.Fatalf("expected ErrLockBusy out of scope, got %v", err)
+	}
+
+	err = reentered.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	locks.AssertHeld(t, "order.42")
+	err = lock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	locks.AssertNotHeld(t, "order.42")
+	if acquisitions := locks.Acquisitions(); len(acquisitions) != 1 {
+		t.Fatalf("expected single acquisition, got %v", acquisitions)
+	}
+}
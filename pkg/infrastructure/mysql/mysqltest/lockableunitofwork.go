@@ -0,0 +1,8 @@
+package mysqltest
+
+import "github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+
+// NewLockableUnitOfWorkFactory combines fakes, so locks and units of work are recorded by them
+func NewLockableUnitOfWorkFactory(lockFactory *LockFactory, unitOfWorkFactory *UnitOfWorkFactory) mysql.LockableUnitOfWorkFactory {
+	return mysql.NewLockableUnitOfWorkFactory(lockFactory, unitOfWorkFactory)
+}
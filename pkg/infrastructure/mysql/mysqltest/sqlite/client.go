@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+var errUnexpectedConnection = errors.New("unexpected sqlite connection type")
+
+// Client is mysql.TransactionalClient backed by SQLite database in temporary directory,
+// it emulates MySQL named lock functions and KILL QUERY, so mysql.LockFactory works against it
+type Client struct {
+	mysql.TransactionalClient
+	db  *sqlx.DB
+	dir string
+}
+
+// NewTransactionalClient creates empty database, DATABASE() returns database within queries
+func NewTransactionalClient(database string) (*Client, error) {
+	dir, err := os.MkdirTemp("", "mysqltest-sqlite-")
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("_busy_timeout", "5000")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_txlock", "immediate")
+	db := sql.OpenDB(&connector{
+		dsn: "file:" + filepath.Join(dir, database+".db") + "?" + params.Encode(),
+		database: &emulatedDatabase{
+			name:  database,
+			locks: newLockRegistry(),
+		},
+	})
+	dbx := sqlx.NewDb(db, "sqlite3")
+
+	err = dbx.Ping()
+	if err != nil {
+		return nil, errors.Join(err, dbx.Close(), os.RemoveAll(dir))
+	}
+
+	return &Client{
+		TransactionalClient: mysql.NewTransactionalClient(dbx),
+		db:                  dbx,
+		dir:                 dir,
+	}, nil
+}
+
+// DB is used to prepare fixtures and check state outside of units of work
+func (c *Client) DB() *sqlx.DB {
+	return c.db
+}
+
+func (c *Client) Close() error {
+	return errors.Join(c.db.Close(), os.RemoveAll(c.dir))
+}
+
+type emulatedDatabase struct {
+	name          string
+	locks         *lockRegistry
+	lastSessionID int64
+}
+
+type connector struct {
+	dsn      string
+	database *emulatedDatabase
+	driver   sqlite3.SQLiteDriver
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	sqliteConn, ok := conn.(*sqlite3.SQLiteConn)
+	if !ok {
+		return nil, errors.Join(errUnexpectedConnection, conn.Close())
+	}
+
+	session := &session{
+		id:       atomic.AddInt64(&c.database.lastSessionID, 1),
+		database: c.database,
+	}
+	err = session.register(sqliteConn)
+	if err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+
+	return &sessionConn{SQLiteConn: sqliteConn, session: session}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &c.driver
+}
+
+// sessionConn releases named locks of session when connection is closed as MySQL does
+type sessionConn struct {
+	*sqlite3.SQLiteConn
+	session *session
+}
+
+// ExecContext handles KILL QUERY, which interrupts GET_LOCK of another session
+func (conn *sessionConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var session int64
+	_, err := fmt.Sscanf(query, "KILL QUERY %d", &session)
+	if err != nil {
+		return conn.SQLiteConn.ExecContext(ctx, query, args)
+	}
+	conn.session.database.locks.kill(session)
+	return driver.RowsAffected(0), nil
+}
+
+func (conn *sessionConn) Close() error {
+	conn.session.database.locks.releaseAll(conn.session.id)
+	return conn.SQLiteConn.Close()
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestNamedLocksAreHeldBySession(t *testing.T) {
+	client, err := NewTransactionalClient("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	// connections are not kept idle, so closed connection ends its session
+	client.DB().SetMaxIdleConns(0)
+	ctx := context.Background()
+
+	first, err := client.Connection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.Connection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	assertQuery(t, first, 1, "SELECT GET_LOCK('a', 0)")
+	assertQuery(t, first, 1, "SELECT GET_LOCK('a', 0)")
+	assertQuery(t, second, 0, "SELECT GET_LOCK('a', 0)")
+	assertQuery(t, second, 0, "SELECT IS_FREE_LOCK('a')")
+
+	var firstID int64
+	err = first.GetContext(ctx, &firstID, "SELECT CONNECTION_ID()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertQuery(t, second, firstID, "SELECT IS_USED_LOCK('a')")
+	assertQuery(t, second, 0, "SELECT RELEASE_LOCK('a')")
+
+	// ended session releases its locks
+	err = first.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertQuery(t, second, 1, "SELECT GET_LOCK('a', 0)")
+	assertQuery(t, second, 1, "SELECT RELEASE_LOCK('a')")
+
+	var released sql.NullInt64
+	err = second.GetContext(ctx, &released, "SELECT RELEASE_LOCK('a')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released.Valid {
+		t.Fatalf("expected NULL for lock not found, got %d", released.Int64)
+	}
+}
+
+func assertQuery(t *testing.T, conn interface {
+	GetContext(context.Context, interface{}, string, ...interface{}) error
+}, expected int64, query string) {
+	t.Helper()
+	var result int64
+	err := conn.GetContext(context.Background(), &result, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != expected {
+		t.Fatalf("expected %s to return %d, got %d", query, expected, result)
+	}
+}
@@ -0,0 +1,261 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// session registers MySQL functions used by mysql package on SQLite connection
+type session struct {
+	id       int64
+	database *emulatedDatabase
+}
+
+func (s *session) register(conn *sqlite3.SQLiteConn) error {
+	functions := []struct {
+		name string
+		impl interface{}
+		pure bool
+	}{
+		{name: "GET_LOCK", impl: s.getLock},
+		{name: "RELEASE_LOCK", impl: s.releaseLock},
+		{name: "RELEASE_ALL_LOCKS", impl: s.releaseAllLocks},
+		{name: "IS_FREE_LOCK", impl: s.isFreeLock},
+		{name: "IS_USED_LOCK", impl: s.isUsedLock},
+		{name: "CONNECTION_ID", impl: s.connectionID},
+		{name: "DATABASE", impl: s.databaseName},
+		{name: "CONCAT", impl: concat, pure: true},
+	}
+	for _, f := range functions {
+		err := conn.RegisterFunc(f.name, f.impl, f.pure)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLock returns NULL when waiting is interrupted with KILL QUERY
+func (s *session) getLock(name string, timeout interface{}) (interface{}, error) {
+	var seconds float64
+	switch t := timeout.(type) {
+	case int64:
+		seconds = float64(t)
+	case float64:
+		seconds = t
+	default:
+		return nil, fmt.Errorf("unexpected GET_LOCK timeout %v", timeout)
+	}
+	acquired, killed := s.database.locks.acquire(s.id, name, seconds)
+	if killed {
+		return nil, nil
+	}
+	if acquired {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func (s *session) releaseLock(name string) interface{} {
+	released, ok := s.database.locks.release(s.id, name)
+	if !ok {
+		return nil
+	}
+	if released {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+func (s *session) releaseAllLocks() int64 {
+	return s.database.locks.releaseAll(s.id)
+}
+
+func (s *session) isFreeLock(name string) int64 {
+	if _, ok := s.database.locks.holder(name); ok {
+		return 0
+	}
+	return 1
+}
+
+func (s *session) isUsedLock(name string) interface{} {
+	holder, ok := s.database.locks.holder(name)
+	if !ok {
+		return nil
+	}
+	return holder
+}
+
+func (s *session) connectionID() int64 {
+	return s.id
+}
+
+func (s *session) databaseName() string {
+	return s.database.name
+}
+
+func concat(args ...interface{}) interface{} {
+	var b strings.Builder
+	for _, arg := range args {
+		if arg == nil {
+			return nil
+		}
+		if bytes, ok := arg.([]byte); ok {
+			if bytes == nil {
+				return nil
+			}
+			b.Write(bytes)
+			continue
+		}
+		fmt.Fprint(&b, arg)
+	}
+	return b.String()
+}
+
+type heldLock struct {
+	session int64
+	count   int
+}
+
+// lockRegistry follows MySQL 5.7 semantics: locks are reentrant within session and released with session
+type lockRegistry struct {
+	mu      sync.Mutex
+	locks   map[string]*heldLock
+	waiting map[int64]chan struct{}
+	changed chan struct{}
+}
+
+func newLockRegistry() *lockRegistry {
+	return &lockRegistry{
+		locks:   make(map[string]*heldLock),
+		waiting: make(map[int64]chan struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire waits infinitely for negative timeout
+func (r *lockRegistry) acquire(session int64, name string, timeout float64) (acquired, killed bool) {
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(time.Duration(timeout * float64(time.Second)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	kill := r.wait(session)
+	defer r.stopWaiting(session)
+
+	for {
+		changed, ok := r.tryAcquire(session, name)
+		if ok {
+			return true, false
+		}
+		select {
+		case <-changed:
+		case <-expired:
+			return false, false
+		case <-kill:
+			return false, true
+		}
+	}
+}
+
+func (r *lockRegistry) wait(session int64) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kill := make(chan struct{})
+	r.waiting[session] = kill
+	return kill
+}
+
+func (r *lockRegistry) stopWaiting(session int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.waiting, session)
+}
+
+// kill interrupts GET_LOCK waiting in session, it does nothing for idle session
+func (r *lockRegistry) kill(session int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kill, ok := r.waiting[session]
+	if ok {
+		close(kill)
+		delete(r.waiting, session)
+	}
+}
+
+func (r *lockRegistry) tryAcquire(session int64, name string) (<-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lock, ok := r.locks[name]
+	if !ok {
+		r.locks[name] = &heldLock{session: session, count: 1}
+		return nil, true
+	}
+	if lock.session == session {
+		lock.count++
+		return nil, true
+	}
+	return r.changed, false
+}
+
+// release returns false when lock does not exist
+func (r *lockRegistry) release(session int64, name string) (released, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lock, ok := r.locks[name]
+	if !ok {
+		return false, false
+	}
+	if lock.session != session {
+		return false, true
+	}
+	lock.count--
+	if lock.count == 0 {
+		delete(r.locks, name)
+		r.notify()
+	}
+	return true, true
+}
+
+func (r *lockRegistry) releaseAll(session int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var released int64
+	for name, lock := range r.locks {
+		if lock.session == session {
+			released += int64(lock.count)
+			delete(r.locks, name)
+		}
+	}
+	if released > 0 {
+		r.notify()
+	}
+	return released
+}
+
+func (r *lockRegistry) holder(name string) (int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lock, ok := r.locks[name]
+	if !ok {
+		return 0, false
+	}
+	return lock.session, true
+}
+
+func (r *lockRegistry) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
@@ -0,0 +1,259 @@
+package mysqltest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+type Completion struct {
+	// Depth is 1 for the outermost unit of work
+	Depth int
+	Err   error
+}
+
+// UnitOfWorkFactory fakes mysql.UnitOfWorkFactory, unit of work opened within scope of not completed unit
+// of work is nested like in mysql.UnitOfWorkFactory, e.g. with the same context of mysql.WithScope or with
+// context derived from Context(). Nested units of work behave like savepoints: failed nested unit of work
+// drops its commit hooks and does not affect the outermost one, unless SetRollbackOnly is called
+type UnitOfWorkFactory struct {
+	// Client is used by all units of work
+	Client *ClientContext
+	// Err is returned instead of unit of work when set
+	Err error
+
+	mu          sync.Mutex
+	commits     int
+	rollbacks   int
+	open        int
+	maxDepth    int
+	completions []Completion
+	// scopes are not completed units of work by scope id in order of opening
+	scopes map[uint64][]*unitOfWork
+}
+
+func NewUnitOfWorkFactory() *UnitOfWorkFactory {
+	return &UnitOfWorkFactory{
+		Client: &ClientContext{},
+		scopes: make(map[uint64][]*unitOfWork),
+	}
+}
+
+func (f *UnitOfWorkFactory) UnitOfWork(ctx context.Context) (mysql.UnitOfWork, error) {
+	return f.UnitOfWorkWithOptions(ctx, mysql.TransactionOptions{})
+}
+
+func (f *UnitOfWorkFactory) UnitOfWorkWithOptions(ctx context.Context, _ mysql.TransactionOptions) (mysql.UnitOfWork, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	if f.scopes == nil {
+		f.scopes = make(map[uint64][]*unitOfWork)
+	}
+
+	ctx = mysql.WithScope(ctx)
+	scopeID, _ := mysql.ScopeID(ctx)
+	uow := &unitOfWork{
+		factory: f,
+		ctx:     ctx,
+		scopeID: scopeID,
+		depth:   1,
+	}
+	if open := f.scopes[scopeID]; len(open) > 0 {
+		uow.parent = open[len(open)-1]
+		uow.depth = uow.parent.depth + 1
+	}
+	f.scopes[scopeID] = append(f.scopes[scopeID], uow)
+
+	f.open++
+	if uow.depth > f.maxDepth {
+		f.maxDepth = uow.depth
+	}
+	return uow, nil
+}
+
+func (f *UnitOfWorkFactory) Commits() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commits
+}
+
+func (f *UnitOfWorkFactory) Rollbacks() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rollbacks
+}
+
+func (f *UnitOfWorkFactory) MaxDepth() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.maxDepth
+}
+
+func (f *UnitOfWorkFactory) Completions() []Completion {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Completion(nil), f.completions...)
+}
+
+func (f *UnitOfWorkFactory) AssertCommitted(t testing.TB, commits int) {
+	t.Helper()
+	if actual := f.Commits(); actual != commits {
+		t.Errorf("mysqltest: expected %d commits, got %d", commits, actual)
+	}
+}
+
+func (f *UnitOfWorkFactory) AssertRolledBack(t testing.TB, rollbacks int) {
+	t.Helper()
+	if actual := f.Rollbacks(); actual != rollbacks {
+		t.Errorf("mysqltest: expected %d rollbacks, got %d", rollbacks, actual)
+	}
+}
+
+func (f *UnitOfWorkFactory) AssertMaxDepth(t testing.TB, depth int) {
+	t.Helper()
+	if actual := f.MaxDepth(); actual != depth {
+		t.Errorf("mysqltest: expected max nesting depth %d, got %d", depth, actual)
+	}
+}
+
+func (f *UnitOfWorkFactory) AssertAllCompleted(t testing.TB) {
+	t.Helper()
+	f.mu.Lock()
+	open := f.open
+	f.mu.Unlock()
+	if open != 0 {
+		t.Errorf("mysqltest: %d units of work are not completed", open)
+	}
+}
+
+func (f *UnitOfWorkFactory) complete(uow *unitOfWork, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.open--
+	f.leave(uow)
+	f.completions = append(f.completions, Completion{Depth: uow.depth, Err: err})
+	if uow.parent != nil {
+		return
+	}
+	if err != nil {
+		f.rollbacks++
+		return
+	}
+	f.commits++
+}
+
+// leave removes unit of work from not completed units of work of its scope
+func (f *UnitOfWorkFactory) leave(uow *unitOfWork) {
+	open := f.scopes[uow.scopeID]
+	for i, u := range open {
+		if u == uow {
+			open = append(open[:i], open[i+1:]...)
+			break
+		}
+	}
+	if len(open) == 0 {
+		delete(f.scopes, uow.scopeID)
+		return
+	}
+	f.scopes[uow.scopeID] = open
+}
+
+type unitOfWork struct {
+	factory *UnitOfWorkFactory
+	parent  *unitOfWork
+	ctx     context.Context
+	scopeID uint64
+	depth   int
+
+	mu            sync.Mutex
+	completed     bool
+	rollbackOnly  bool
+	commitHooks   []mysql.UnitOfWorkHook
+	rollbackHooks []mysql.UnitOfWorkHook
+}
+
+func (u *unitOfWork) Complete(err error) error {
+	u.mu.Lock()
+	if u.completed {
+		u.mu.Unlock()
+		return err
+	}
+	u.completed = true
+	commitHooks, rollbackHooks := u.commitHooks, u.rollbackHooks
+	if err == nil && u.rollbackOnly {
+		err = mysql.ErrTransactionRollbackOnly
+	}
+	u.mu.Unlock()
+
+	u.factory.complete(u, err)
+
+	switch {
+	case err != nil:
+		return errors.Join(err, runHooks(u.ctx, rollbackHooks))
+	case u.parent != nil:
+		for _, hook := range commitHooks {
+			u.parent.OnCommit(hook)
+		}
+		for _, hook := range rollbackHooks {
+			u.parent.OnRollback(hook)
+		}
+		return nil
+	default:
+		return runHooks(u.ctx, commitHooks)
+	}
+}
+
+func (u *unitOfWork) ClientContext() mysql.ClientContext {
+	return u.factory.Client
+}
+
+func (u *unitOfWork) Context() context.Context {
+	return u.ctx
+}
+
+// SetRollbackOnly makes the outermost unit of work roll back with mysql.ErrTransactionRollbackOnly
+func (u *unitOfWork) SetRollbackOnly() {
+	root := u
+	for root.parent != nil {
+		root = root.parent
+	}
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.rollbackOnly = true
+}
+
+func (u *unitOfWork) Nested() bool {
+	return u.parent != nil
+}
+
+func (u *unitOfWork) OnCommit(hook mysql.UnitOfWorkHook) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.commitHooks = append(u.commitHooks, hook)
+}
+
+func (u *unitOfWork) OnRollback(hook mysql.UnitOfWorkHook) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.rollbackHooks = append(u.rollbackHooks, hook)
+}
+
+func runHooks(ctx context.Context, hooks []mysql.UnitOfWorkHook) error {
+	var err error
+	for _, hook := range hooks {
+		err = errors.Join(err, hook(ctx))
+	}
+	if err != nil {
+		return errors.Join(mysql.ErrUnitOfWorkHookFailed, err)
+	}
+	return nil
+}
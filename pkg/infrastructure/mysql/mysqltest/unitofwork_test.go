@@ -0,0 +1,119 @@
+package mysqltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+var errTest = errors.New("test error")
+
+func TestUnitOfWorkOfSameScopeIsNested(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+	ctx := mysql.WithScope(context.Background())
+
+	outer, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = errors.Join(nested.Complete(nil), outer.Complete(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	factory.AssertCommitted(t, 1)
+	factory.AssertMaxDepth(t, 2)
+	factory.AssertAllCompleted(t)
+}
+
+func TestUnitOfWorkOfContextWithoutScopeIsOutermost(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+	ctx := context.Background()
+
+	first, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := factory.UnitOfWork(first.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = errors.Join(nested.Complete(nil), second.Complete(nil), first.Complete(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	factory.AssertCommitted(t, 2)
+	factory.AssertMaxDepth(t, 2)
+}
+
+func TestFailedNestedUnitOfWorkDropsItsCommitHooks(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+
+	outer, err := factory.UnitOfWork(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var committed, rolledBack bool
+	nested.OnCommit(func(context.Context) error {
+		committed = true
+		return nil
+	})
+	nested.OnRollback(func(context.Context) error {
+		rolledBack = true
+		return nil
+	})
+	err = nested.Complete(errTest)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected nested error, got %v", err)
+	}
+
+	err = outer.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed || !rolledBack {
+		t.Fatalf("expected only rollback hook to run, commit %v, rollback %v", committed, rolledBack)
+	}
+	factory.AssertCommitted(t, 1)
+	factory.AssertRolledBack(t, 0)
+}
+
+func TestSetRollbackOnlyRollsBackOutermostUnitOfWork(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+
+	outer, err := factory.UnitOfWork(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested.SetRollbackOnly()
+	err = nested.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = outer.Complete(nil)
+	if !errors.Is(err, mysql.ErrTransactionRollbackOnly) {
+		t.Fatalf("expected ErrTransactionRollbackOnly, got %v", err)
+	}
+	factory.AssertRolledBack(t, 1)
+}
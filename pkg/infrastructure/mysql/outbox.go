@@ -0,0 +1,158 @@
+package mysql
+
+import (
+	"context"
+	// include embed for outbox schema
+	_ "embed"
+	"time"
+
+	applogger "github.com/tss-calculator/go-lib/pkg/application/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OutboxSchema is DDL of the table used by Outbox and OutboxRelay
+//
+//go:embed outbox.sql
+var OutboxSchema string
+
+var DefaultOutboxRelayConfig = OutboxRelayConfig{
+	BatchSize:    100,
+	PollInterval: time.Second,
+}
+
+type OutboxMessage struct {
+	Topic   string
+	Key     string
+	Payload []byte
+}
+
+type OutboxRecord struct {
+	ID        int64     `db:"id"`
+	Topic     string    `db:"topic"`
+	Key       string    `db:"message_key"`
+	Payload   []byte    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Outbox interface {
+	// Append stores messages within transaction of client, e.g. UnitOfWork.ClientContext()
+	Append(ctx context.Context, client ClientContext, messages ...OutboxMessage) error
+}
+
+type OutboxPublisher interface {
+	Publish(ctx context.Context, records []OutboxRecord) error
+}
+
+type OutboxRelay interface {
+	// Run relays pending messages until ctx is done
+	Run(ctx context.Context)
+	// RelayBatch publishes one batch of pending messages and returns number of delivered ones
+	RelayBatch(ctx context.Context) (int, error)
+}
+
+// OutboxRelayConfig fields not greater than zero are taken from DefaultOutboxRelayConfig
+type OutboxRelayConfig struct {
+	BatchSize int
+	// PollInterval is time between batches, when there is no pending messages left
+	PollInterval time.Duration
+}
+
+func NewOutbox() Outbox {
+	return &outbox{}
+}
+
+type outbox struct{}
+
+func (o *outbox) Append(ctx context.Context, client ClientContext, messages ...OutboxMessage) error {
+	const sqlQuery = "INSERT INTO outbox (topic, message_key, payload) VALUES (?, ?, ?)"
+	for _, message := range messages {
+		_, err := client.ExecContext(ctx, sqlQuery, message.Topic, message.Key, message.Payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewOutboxRelay(
+	unitOfWorkFactory UnitOfWorkFactory,
+	publisher OutboxPublisher,
+	logger applogger.Logger,
+	config OutboxRelayConfig,
+) OutboxRelay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultOutboxRelayConfig.BatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultOutboxRelayConfig.PollInterval
+	}
+	return &outboxRelay{
+		unitOfWorkFactory: unitOfWorkFactory,
+		publisher:         publisher,
+		logger:            logger,
+		config:            config,
+	}
+}
+
+type outboxRelay struct {
+	unitOfWorkFactory UnitOfWorkFactory
+	publisher         OutboxPublisher
+	logger            applogger.Logger
+	config            OutboxRelayConfig
+}
+
+func (relay *outboxRelay) Run(ctx context.Context) {
+	for {
+		n, err := relay.RelayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			relay.logger.Error(err, "failed relay outbox messages")
+		}
+		if n > 0 && n >= relay.config.BatchSize {
+			continue
+		}
+		if sleep(ctx, relay.config.PollInterval) != nil {
+			return
+		}
+	}
+}
+
+func (relay *outboxRelay) RelayBatch(ctx context.Context) (n int, err error) {
+	uow, err := relay.unitOfWorkFactory.UnitOfWork(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = uow.Complete(err)
+		if err != nil {
+			n = 0
+		}
+	}()
+
+	const selectQuery = `SELECT id, topic, message_key, payload, created_at FROM outbox
+		WHERE delivered_at IS NULL ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED`
+	var records []OutboxRecord
+	err = uow.ClientContext().SelectContext(uow.Context(), &records, selectQuery, relay.config.BatchSize)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+
+	err = relay.publisher.Publish(uow.Context(), records)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	updateQuery, args, err := sqlx.In("UPDATE outbox SET delivered_at = NOW(6) WHERE id IN (?)", ids)
+	if err != nil {
+		return 0, err
+	}
+	_, err = uow.ClientContext().ExecContext(uow.Context(), updateQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
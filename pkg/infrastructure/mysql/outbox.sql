@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id           BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    topic        VARCHAR(255)    NOT NULL,
+    message_key  VARCHAR(255)    NOT NULL DEFAULT '',
+    payload      LONGBLOB        NOT NULL,
+    created_at   DATETIME(6)     NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    delivered_at DATETIME(6)     NULL,
+    PRIMARY KEY (id),
+    INDEX outbox_pending_idx (delivered_at, id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_unicode_ci;
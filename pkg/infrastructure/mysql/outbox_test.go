@@ -0,0 +1,112 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+type publisherFunc func(ctx context.Context, records []mysql.OutboxRecord) error
+
+func (f publisherFunc) Publish(ctx context.Context, records []mysql.OutboxRecord) error {
+	return f(ctx, records)
+}
+
+func TestOutboxAppendsMessagesWithinClient(t *testing.T) {
+	client := &mysqltest.ClientContext{}
+
+	err := mysql.NewOutbox().Append(context.Background(), client,
+		mysql.OutboxMessage{Topic: "order", Key: "42", Payload: []byte("created")},
+		mysql.OutboxMessage{Topic: "order", Key: "43", Payload: []byte("created")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := client.Queries()
+	if len(queries) != 2 || !strings.HasPrefix(queries[0].Query, "INSERT INTO outbox") {
+		t.Fatalf("expected two inserts, got %v", queries)
+	}
+	if !reflect.DeepEqual(queries[1].Args, []interface{}{"order", "43", []byte("created")}) {
+		t.Fatalf("unexpected insert args %v", queries[1].Args)
+	}
+}
+
+func TestOutboxRelayMarksPublishedRecordsDelivered(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+	factory.Client.SelectFunc = selectOutboxRecords(1, 2)
+	var published []mysql.OutboxRecord
+	relay := mysql.NewOutboxRelay(factory, publisherFunc(func(_ context.Context, records []mysql.OutboxRecord) error {
+		published = records
+		return nil
+	}), &testLogger{}, mysql.OutboxRelayConfig{})
+
+	n, err := relay.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(published) != 2 {
+		t.Fatalf("expected 2 relayed records, relayed %d, published %v", n, published)
+	}
+
+	queries := factory.Client.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("expected select and update, got %v", queries)
+	}
+	// zero batch size is taken from default config
+	if !reflect.DeepEqual(queries[0].Args, []interface{}{mysql.DefaultOutboxRelayConfig.BatchSize}) {
+		t.Fatalf("expected default batch size, got %v", queries[0].Args)
+	}
+	if !strings.HasPrefix(queries[1].Query, "UPDATE outbox SET delivered_at") ||
+		!reflect.DeepEqual(queries[1].Args, []interface{}{int64(1), int64(2)}) {
+		t.Fatalf("expected records to be marked delivered, got %v", queries[1])
+	}
+	factory.AssertCommitted(t, 1)
+}
+
+func TestOutboxRelayRollsBackFailedPublish(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+	factory.Client.SelectFunc = selectOutboxRecords(1)
+	relay := mysql.NewOutboxRelay(factory, publisherFunc(func(context.Context, []mysql.OutboxRecord) error {
+		return errTest
+	}), &testLogger{}, mysql.DefaultOutboxRelayConfig)
+
+	n, err := relay.RelayBatch(context.Background())
+	if !errors.Is(err, errTest) || n != 0 {
+		t.Fatalf("expected publish error without relayed records, got %d, %v", n, err)
+	}
+	if queries := factory.Client.Queries(); len(queries) != 1 {
+		t.Fatalf("expected records not to be marked delivered, got %v", queries)
+	}
+	factory.AssertRolledBack(t, 1)
+}
+
+func TestOutboxRelayWaitsDefaultPollIntervalForZeroConfig(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+	relay := mysql.NewOutboxRelay(factory, publisherFunc(func(context.Context, []mysql.OutboxRecord) error {
+		return nil
+	}), &testLogger{}, mysql.OutboxRelayConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	relay.Run(ctx)
+
+	if queries := factory.Client.Queries(); len(queries) != 1 {
+		t.Fatalf("expected single poll within poll interval, got %d", len(queries))
+	}
+}
+
+func selectOutboxRecords(ids ...int64) func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return func(_ context.Context, dest interface{}, _ string, _ ...interface{}) error {
+		records := dest.(*[]mysql.OutboxRecord)
+		for _, id := range ids {
+			*records = append(*records, mysql.OutboxRecord{ID: id, Topic: "order", Payload: []byte("created")})
+		}
+		return nil
+	}
+}
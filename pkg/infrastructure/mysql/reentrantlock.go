@@ -0,0 +1,286 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/common/maybe"
+)
+
+// NewReentrantLockFactory makes locks of factory reentrant within context scope like locks of LockFactory
+// backends of the package, e.g. for custom LockFactory or fakes in tests
+func NewReentrantLockFactory(factory LockFactory) LockFactory {
+	return &reentrantLockFactory{
+		factory: factory,
+	}
+}
+
+type reentrantLockFactory struct {
+	factory   LockFactory
+	reentrant reentrantLocks
+}
+
+func (factory *reentrantLockFactory) NewLock(ctx context.Context, lockName string, timeout time.Duration) (Lock, error) {
+	return factory.reentrant.newLock(ctx, []string{lockName}, func(lockNames []string) (keyedLock, error) {
+		lock, err := factory.factory.NewLock(ctx, lockName, timeout)
+		return newNamedLock(lock, lockNames), err
+	})
+}
+
+func (factory *reentrantLockFactory) TryLock(ctx context.Context, lockName string) (Lock, error) {
+	return factory.reentrant.newLock(ctx, []string{lockName}, func(lockNames []string) (keyedLock, error) {
+		lock, err := factory.factory.TryLock(ctx, lockName)
+		return newNamedLock(lock, lockNames), err
+	})
+}
+
+func (factory *reentrantLockFactory) NewMultiLock(ctx context.Context, lockNames []string, timeout time.Duration) (Lock, error) {
+	return factory.reentrant.newLock(ctx, sortedUniqueLockNames(lockNames), func(lockNames []string) (keyedLock, error) {
+		lock, err := factory.factory.NewMultiLock(ctx, lockNames, timeout)
+		return newNamedLock(lock, lockNames), err
+	})
+}
+
+func (factory *reentrantLockFactory) IsLocked(ctx context.Context, lockName string) (bool, error) {
+	return factory.factory.IsLocked(ctx, lockName)
+}
+
+func (factory *reentrantLockFactory) Holder(ctx context.Context, lockName string) (maybe.Maybe[string], error) {
+	return factory.factory.Holder(ctx, lockName)
+}
+
+// namedLock is keyed by lock names, since lock keys of wrapped factory are unknown
+type namedLock struct {
+	Lock
+	lockNames []string
+}
+
+// newNamedLock returns nil for nil lock, so failed acquisition is not wrapped into not nil keyedLock
+func newNamedLock(lock Lock, lockNames []string) keyedLock {
+	if lock == nil {
+		return nil
+	}
+	return &namedLock{
+		Lock:      lock,
+		lockNames: lockNames,
+	}
+}
+
+func (l *namedLock) heldKeys() []string {
+	return l.lockNames
+}
+
+// keyedLock is lock of any backend holding several lock keys
+type keyedLock interface {
+	Lock
+	heldKeys() []string
+}
+
+// reentrantLocks makes locks of backend reentrant within context scope, acquisition of key already held
+// within the scope is counted instead of acquired again and the last release unlocks it
+type reentrantLocks struct {
+	mu sync.Mutex
+}
+
+// scopeLocks are locks held within scope by lock key
+type scopeLocks struct {
+	locks map[string]*scopeLock
+}
+
+type scopeLock struct {
+	keyedLock
+	count int
+}
+
+// newLock calls acquire for lock keys not held within scope, context without scope starts new scope
+// carried by Context of the lock
+func (r *reentrantLocks) newLock(
+	ctx context.Context,
+	lockKeys []string,
+	acquire func(lockKeys []string) (keyedLock, error),
+) (Lock, error) {
+	ctx, _ = joinScope(ctx)
+	held, missing := r.retain(ctx, lockKeys)
+	if len(missing) > 0 {
+		lock, err := acquire(missing)
+		if err != nil {
+			return nil, errors.Join(err, r.release(ctx, held))
+		}
+		held = append(held, r.store(ctx, lock))
+	}
+	return newReentrantLock(ctx, r, lockKeys, held), nil
+}
+
+// retain returns locks held within scope for lock keys and lock keys to acquire
+func (r *reentrantLocks) retain(ctx context.Context, lockKeys []string) (held []*scopeLock, missing []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locks, ok := loadFromScope[*scopeLocks](ctx, r)
+	if !ok {
+		return nil, lockKeys
+	}
+	for _, lockKey := range lockKeys {
+		lock, ok := locks.locks[lockKey]
+		if !ok {
+			missing = append(missing, lockKey)
+			continue
+		}
+		if !containsScopeLock(held, lock) {
+			lock.count++
+			held = append(held, lock)
+		}
+	}
+	return held, missing
+}
+
+func (r *reentrantLocks) store(ctx context.Context, keyedLock keyedLock) *scopeLock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locks, ok := loadFromScope[*scopeLocks](ctx, r)
+	if !ok {
+		locks = &scopeLocks{locks: make(map[string]*scopeLock)}
+		storeToScope(ctx, r, locks)
+	}
+	lock := &scopeLock{keyedLock: keyedLock, count: 1}
+	for _, lockKey := range keyedLock.heldKeys() {
+		locks.locks[lockKey] = lock
+	}
+	return lock
+}
+
+// release unlocks released locks in reverse order without lock, since backends unlock with queries
+func (r *reentrantLocks) release(ctx context.Context, held []*scopeLock) error {
+	released := r.releaseRefs(ctx, held)
+
+	var err error
+	for i := len(released) - 1; i >= 0; i-- {
+		err = errors.Join(err, released[i].Unlock())
+	}
+	return err
+}
+
+func (r *reentrantLocks) releaseRefs(ctx context.Context, held []*scopeLock) (released []*scopeLock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locks, _ := loadFromScope[*scopeLocks](ctx, r)
+	for _, lock := range held {
+		lock.count--
+		if lock.count > 0 {
+			continue
+		}
+		released = append(released, lock)
+		if locks == nil {
+			continue
+		}
+		for _, lockKey := range lock.heldKeys() {
+			if locks.locks[lockKey] == lock {
+				delete(locks.locks, lockKey)
+			}
+		}
+	}
+	if locks != nil && len(locks.locks) == 0 {
+		deleteFromScope(ctx, r)
+	}
+	return released
+}
+
+func containsScopeLock(locks []*scopeLock, lock *scopeLock) bool {
+	for _, l := range locks {
+		if l == lock {
+			return true
+		}
+	}
+	return false
+}
+
+// reentrantLock is lock handle for single acquisition within scope, it is lost when any of held locks is lost
+type reentrantLock struct {
+	ctx      context.Context
+	locks    *reentrantLocks
+	lockKeys []string
+	held     []*scopeLock
+
+	lockCtx    context.Context
+	cancel     context.CancelCauseFunc
+	lost       chan struct{}
+	lostOnce   sync.Once
+	released   chan struct{}
+	unlockOnce sync.Once
+}
+
+func newReentrantLock(ctx context.Context, locks *reentrantLocks, lockKeys []string, held []*scopeLock) *reentrantLock {
+	lock := &reentrantLock{
+		ctx:      ctx,
+		locks:    locks,
+		lockKeys: lockKeys,
+		held:     held,
+		lost:     make(chan struct{}),
+		released: make(chan struct{}),
+	}
+	lock.lockCtx, lock.cancel = context.WithCancelCause(ctx)
+	for _, l := range held {
+		go lock.watch(l)
+	}
+	return lock
+}
+
+func (l *reentrantLock) Unlock() error {
+	err := ErrLockNotLocked
+	l.unlockOnce.Do(func() {
+		close(l.released)
+		l.cancel(nil)
+		err = l.locks.release(l.ctx, l.held)
+	})
+	return err
+}
+
+func (l *reentrantLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *reentrantLock) Context() context.Context {
+	return l.lockCtx
+}
+
+// FencingTokens are tokens issued when lock keys were acquired within scope first time
+func (l *reentrantLock) FencingTokens() []FencingToken {
+	var tokens []FencingToken
+	for _, held := range l.held {
+		for _, token := range held.FencingTokens() {
+			lockKey := token.lockKey
+			if lockKey == "" {
+				// tokens of wrapped factory are keyed by lock names
+				lockKey = token.LockName
+			}
+			if containsLockKey(l.lockKeys, lockKey) {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+func (l *reentrantLock) watch(held *scopeLock) {
+	select {
+	case <-held.Lost():
+		l.lostOnce.Do(func() {
+			close(l.lost)
+			l.cancel(ErrLockLost)
+		})
+	case <-l.released:
+	}
+}
+
+func containsLockKey(lockKeys []string, lockKey string) bool {
+	for _, key := range lockKeys {
+		if key == lockKey {
+			return true
+		}
+	}
+	return false
+}
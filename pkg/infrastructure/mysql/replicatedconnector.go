@@ -0,0 +1,266 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var DefaultReplicaConfig = ReplicaConfig{
+	MaxLag:        time.Second * 10,
+	CheckInterval: time.Second * 5,
+	CheckTimeout:  time.Second,
+}
+
+type ReplicaConfig struct {
+	// MaxLag evicts replica lagging behind primary more than MaxLag, zero disables lag check
+	MaxLag time.Duration
+	// CheckInterval and CheckTimeout are taken from DefaultReplicaConfig when they are zero
+	CheckInterval time.Duration
+	CheckTimeout  time.Duration
+}
+
+type ReplicatedConnector interface {
+	// Open fails when primary is unavailable, unavailable replicas are evicted until they pass health check
+	Open(primary DSN, replicas []DSN, cfg Config, replicaConfig ReplicaConfig) error
+	Close() error
+
+	// TransactionalClient is bound to primary, use it for UnitOfWorkFactory
+	TransactionalClient() TransactionalClient
+	// ReadOnlyClient balances queries across healthy replicas, falls back to primary when there are none,
+	// queries within transaction of context scope go to the transaction, statements are executed on primary
+	ReadOnlyClient() ClientContext
+
+	// HealthReporter reports health of primary and stats of primary and replicas
+	HealthReporter
+}
+
+func NewReplicatedConnector() ReplicatedConnector {
+	return &replicatedConnector{}
+}
+
+type replicatedConnector struct {
+	primary  connector
+	replicas []*replica
+	config   ReplicaConfig
+	next     uint64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+type replica struct {
+	db *sqlx.DB
+
+	mu      sync.RWMutex
+	healthy bool
+}
+
+func (c *replicatedConnector) Open(primary DSN, replicas []DSN, cfg Config, replicaConfig ReplicaConfig) error {
+	err := c.primary.Open(primary, cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, dsn := range replicas {
+		db, err := newDB(dsn, cfg)
+		if err != nil {
+			return errors.Join(err, c.closeDBs())
+		}
+		c.replicas = append(c.replicas, &replica{db: db})
+	}
+	c.start(replicaConfig)
+	return nil
+}
+
+// start checks replicas of opened databases and watches them until Close
+func (c *replicatedConnector) start(replicaConfig ReplicaConfig) {
+	if replicaConfig.CheckInterval <= 0 {
+		replicaConfig.CheckInterval = DefaultReplicaConfig.CheckInterval
+	}
+	if replicaConfig.CheckTimeout <= 0 {
+		replicaConfig.CheckTimeout = DefaultReplicaConfig.CheckTimeout
+	}
+	c.config = replicaConfig
+
+	c.checkReplicas()
+	c.stop = make(chan struct{})
+	c.wg.Add(1)
+	go c.watchReplicas()
+}
+
+func (c *replicatedConnector) Close() error {
+	if c.stop != nil {
+		close(c.stop)
+		c.wg.Wait()
+		c.stop = nil
+	}
+	return c.closeDBs()
+}
+
+func (c *replicatedConnector) TransactionalClient() TransactionalClient {
+	return c.primary.TransactionalClient()
+}
+
+func (c *replicatedConnector) ReadOnlyClient() ClientContext {
+	return &readOnlyClient{connector: c}
+}
+
+func (c *replicatedConnector) Health(ctx context.Context) error {
+	return c.primary.Health(ctx)
+}
+
+func (c *replicatedConnector) Stats() Stats {
+	stats := c.primary.Stats()
+	for _, r := range c.replicas {
+		r.mu.RLock()
+		healthy := r.healthy
+		r.mu.RUnlock()
+		stats.Replicas = append(stats.Replicas, ReplicaStats{
+			Healthy: healthy,
+			DB:      r.db.Stats(),
+		})
+	}
+	return stats
+}
+
+func (c *replicatedConnector) closeDBs() error {
+	var err error
+	for _, r := range c.replicas {
+		err = errors.Join(err, r.db.Close())
+	}
+	c.replicas = nil
+	return errors.Join(err, c.primary.Close())
+}
+
+func (c *replicatedConnector) watchReplicas() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.checkReplicas()
+		}
+	}
+}
+
+func (c *replicatedConnector) checkReplicas() {
+	for _, r := range c.replicas {
+		healthy := c.checkReplica(r)
+		r.mu.Lock()
+		r.healthy = healthy
+		r.mu.Unlock()
+	}
+}
+
+func (c *replicatedConnector) checkReplica(r *replica) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.CheckTimeout)
+	defer cancel()
+
+	err := r.db.PingContext(ctx)
+	if err != nil {
+		return false
+	}
+	if c.config.MaxLag <= 0 {
+		return true
+	}
+	lag, ok, err := replicationLag(ctx, r.db)
+	if err != nil {
+		return false
+	}
+	return ok && lag <= c.config.MaxLag
+}
+
+// replicationLag returns false when replication is stopped or broken
+func replicationLag(ctx context.Context, db *sqlx.DB) (time.Duration, bool, error) {
+	rows, err := db.QueryxContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// servers before 8.0.22 support only legacy syntax
+		rows, err = db.QueryxContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		// server is not a replica
+		return 0, true, rows.Err()
+	}
+	status := make(map[string]interface{})
+	err = rows.MapScan(status)
+	if err != nil {
+		return 0, false, err
+	}
+
+	secondsBehind, ok := status["Seconds_Behind_Source"]
+	if !ok {
+		secondsBehind = status["Seconds_Behind_Master"]
+	}
+	var seconds sql.NullInt64
+	err = seconds.Scan(secondsBehind)
+	if err != nil || !seconds.Valid {
+		return 0, false, err
+	}
+	return time.Duration(seconds.Int64) * time.Second, true, nil
+}
+
+func (c *replicatedConnector) writer(ctx context.Context) ClientContext {
+	if stx, ok := activeTransaction(ctx); ok {
+		return stx
+	}
+	return c.primary.db
+}
+
+func (c *replicatedConnector) reader(ctx context.Context) ClientContext {
+	if stx, ok := activeTransaction(ctx); ok {
+		return stx
+	}
+
+	n := len(c.replicas)
+	start := atomic.AddUint64(&c.next, 1)
+	for i := 0; i < n; i++ {
+		r := c.replicas[(start+uint64(i))%uint64(n)]
+		r.mu.RLock()
+		healthy := r.healthy
+		r.mu.RUnlock()
+		if healthy {
+			return r.db
+		}
+	}
+	return c.primary.db
+}
+
+type readOnlyClient struct {
+	connector *replicatedConnector
+}
+
+func (client *readOnlyClient) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return client.connector.reader(ctx).QueryContext(ctx, query, args...)
+}
+
+func (client *readOnlyClient) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return client.connector.reader(ctx).QueryRowContext(ctx, query, args...)
+}
+
+func (client *readOnlyClient) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return client.connector.writer(ctx).ExecContext(ctx, query, args...)
+}
+
+func (client *readOnlyClient) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return client.connector.reader(ctx).SelectContext(ctx, dest, query, args...)
+}
+
+func (client *readOnlyClient) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return client.connector.reader(ctx).GetContext(ctx, dest, query, args...)
+}
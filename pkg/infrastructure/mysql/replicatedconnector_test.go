@@ -0,0 +1,117 @@
+package mysql_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestReadOnlyClientBalancesQueriesAcrossReplicas(t *testing.T) {
+	primary, replicas := newReplicatedDatabases(t, 2)
+	connector := mysql.NewReplicatedConnectorOf(primary, replicas, mysql.ReplicaConfig{})
+	t.Cleanup(func() {
+		if err := connector.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	ctx := newContext(t)
+
+	read := make(map[int]int)
+	for i := 0; i < 4; i++ {
+		var id int
+		err := connector.ReadOnlyClient().GetContext(ctx, &id, "SELECT id FROM item")
+		if err != nil {
+			t.Fatal(err)
+		}
+		read[id]++
+	}
+	if read[1] != 2 || read[2] != 2 {
+		t.Fatalf("expected queries to be balanced across replicas, got %v", read)
+	}
+
+	_, err := connector.ReadOnlyClient().ExecContext(ctx, "INSERT INTO item (id) VALUES (3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	err = primary.Get(&count, "SELECT COUNT(*) FROM item WHERE id = 3")
+	if err != nil || count != 1 {
+		t.Fatalf("expected statement to be executed on primary, got %d, %v", count, err)
+	}
+
+	stats := connector.Stats()
+	if len(stats.Replicas) != 2 || !stats.Replicas[0].Healthy || !stats.Replicas[1].Healthy {
+		t.Fatalf("expected healthy replicas, got %+v", stats.Replicas)
+	}
+}
+
+func TestReadOnlyClientFallsBackToPrimaryWithoutHealthyReplicas(t *testing.T) {
+	primary, replicas := newReplicatedDatabases(t, 1)
+	// replication lag of SQLite is unknown, so lag check evicts replica
+	connector := mysql.NewReplicatedConnectorOf(primary, replicas, mysql.ReplicaConfig{MaxLag: mysql.DefaultReplicaConfig.MaxLag})
+	t.Cleanup(func() {
+		if err := connector.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	var id int
+	err := connector.ReadOnlyClient().GetContext(newContext(t), &id, "SELECT id FROM item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 0 {
+		t.Fatalf("expected query on primary, got item of replica %d", id)
+	}
+	if stats := connector.Stats(); stats.Replicas[0].Healthy {
+		t.Fatal("expected replica to be evicted")
+	}
+}
+
+func TestReadOnlyClientQueriesTransactionOfContext(t *testing.T) {
+	primary, replicas := newReplicatedDatabases(t, 1)
+	connector := mysql.NewReplicatedConnectorOf(primary, replicas, mysql.ReplicaConfig{})
+	t.Cleanup(func() {
+		if err := connector.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(connector.TransactionalClient()), nil)
+
+	uow, err := factory.UnitOfWork(newContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, uow.Context(), uow, 3)
+
+	var ids []int
+	err = connector.ReadOnlyClient().SelectContext(uow.Context(), &ids, "SELECT id FROM item ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[1] != 3 {
+		t.Fatalf("expected uncommitted item of transaction, got %v", ids)
+	}
+
+	err = uow.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newReplicatedDatabases creates primary with item 0 and replicas with items 1, 2, etc.
+func newReplicatedDatabases(t *testing.T, replicas int) (*sqlx.DB, []*sqlx.DB) {
+	t.Helper()
+	dbs := make([]*sqlx.DB, 0, replicas+1)
+	for i := 0; i <= replicas; i++ {
+		db := newClient(t).DB()
+		_, err := db.Exec("INSERT INTO item (id) VALUES (?)", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs[0], dbs[1:]
+}
@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+type scopeKey struct{}
+
+var lastScopeID atomic.Uint64
+
+// WithScope attaches scope to context, so connections, transactions and locks opened with it or any derived
+// context are shared, e.g. in request middleware. Outermost unit of work or lock opened with context without
+// scope attaches new scope to its Context(), so only contexts derived from it join them
+func WithScope(ctx context.Context) context.Context {
+	ctx, _ = joinScope(ctx)
+	return ctx
+}
+
+// HandlerWithScope attaches scope to request context, so units of work and locks opened within request share it
+func HandlerWithScope(handler http.Handler) http.Handler {
+	return &scopeHandler{handler: handler}
+}
+
+type scopeHandler struct {
+	handler http.Handler
+}
+
+func (h *scopeHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	h.handler.ServeHTTP(writer, request.WithContext(WithScope(request.Context())))
+}
+
+// ScopeID identifies scope of context, e.g. to match it with LockTimeoutError or to keep state per scope in fakes
+func ScopeID(ctx context.Context) (uint64, bool) {
+	s, ok := scopeFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return s.id, true
+}
+
+// joinScope returns scope of context or attaches new scope to context without scope or with hidden scope
+func joinScope(ctx context.Context) (context.Context, *scope) {
+	if s, ok := scopeFromContext(ctx); ok {
+		return ctx, s
+	}
+	s := &scope{
+		id:     lastScopeID.Add(1),
+		values: make(map[interface{}]interface{}),
+	}
+	return context.WithValue(ctx, scopeKey{}, s), s
+}
+
+// withoutScope hides scope of context, so queries made with it neither join nor share connections and transactions
+func withoutScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, (*scope)(nil))
+}
+
+func scopeFromContext(ctx context.Context) (*scope, bool) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	return s, ok && s != nil
+}
+
+// scope stores values per owner, so several pools and factories can share one context,
+// id identifies scope in diagnostics. Scope is referenced only by contexts, so values are never leaked
+// beyond contexts carrying them
+type scope struct {
+	id     uint64
+	mu     sync.Mutex
+	values map[interface{}]interface{}
+}
+
+func loadFromScope[T any](ctx context.Context, owner interface{}) (T, bool) {
+	var result T
+	s, ok := scopeFromContext(ctx)
+	if !ok {
+		return result, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, ok = s.values[owner].(T)
+	return result, ok
+}
+
+func storeToScope(ctx context.Context, owner, value interface{}) bool {
+	s, ok := scopeFromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[owner] = value
+	return true
+}
+
+func deleteFromScope(ctx context.Context, owner interface{}) {
+	s, ok := scopeFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, owner)
+}
+
+// activeTransaction returns transaction opened within context scope by any UnitOfWorkFactory
+func activeTransaction(ctx context.Context) (*sharedTransaction, bool) {
+	s, ok := scopeFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, value := range s.values {
+		if stx, ok := value.(*sharedTransaction); ok {
+			return stx, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,73 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+type traceKey struct{}
+
+func TestHandlerWithScopeSharesUnitOfWorkOfRequest(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+
+	handler := mysql.HandlerWithScope(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		outer, err := factory.UnitOfWork(request.Context())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		insertItem(t, request.Context(), outer, 1)
+
+		// contexts derived from request context, e.g. by tracing, join unit of work of the request
+		ctx, cancel := context.WithTimeout(context.WithValue(request.Context(), traceKey{}, "trace"), time.Minute)
+		defer cancel()
+		nested, err := factory.UnitOfWork(ctx)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		insertItem(t, ctx, nested, 2)
+
+		err = errors.Join(nested.Complete(nil), outer.Complete(nil))
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assertItems(t, client, 1, 2)
+}
+
+func TestScopeIDIsSharedByDerivedContexts(t *testing.T) {
+	ctx := mysql.WithScope(newContext(t))
+	id, ok := mysql.ScopeID(ctx)
+	if !ok {
+		t.Fatal("expected context to have scope")
+	}
+
+	derived, cancel := context.WithCancel(ctx)
+	defer cancel()
+	derivedID, ok := mysql.ScopeID(derived)
+	if !ok || derivedID != id {
+		t.Fatalf("expected derived context to have scope %d, got %d", id, derivedID)
+	}
+	if mysql.WithScope(derived) != derived {
+		t.Fatal("expected WithScope to keep scope of context")
+	}
+
+	_, ok = mysql.ScopeID(newContext(t))
+	if ok {
+		t.Fatal("expected context without scope")
+	}
+	_, ok = mysql.ScopeID(mysql.WithoutScope(ctx))
+	if ok {
+		t.Fatal("expected hidden scope")
+	}
+}
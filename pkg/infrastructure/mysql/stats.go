@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"database/sql"
+	"sync/atomic"
+)
+
+type Stats struct {
+	DB                sql.DBStats    `json:"db"`
+	SharedConnections int64          `json:"shared_connections"`
+	OpenTransactions  int64          `json:"open_transactions"`
+	HeldLocks         int64          `json:"held_locks"`
+	Replicas          []ReplicaStats `json:"replicas,omitempty"`
+}
+
+type ReplicaStats struct {
+	Healthy bool        `json:"healthy"`
+	DB      sql.DBStats `json:"db"`
+}
+
+// libraryStats is shared by client, connection pool and factories built on top of it
+type libraryStats struct {
+	sharedConnections atomic.Int64
+	openTransactions  atomic.Int64
+	heldLocks         atomic.Int64
+}
+
+type libraryStatsProvider interface {
+	libraryStats() *libraryStats
+}
+
+// libraryStatsOf returns stats of Connector the component is built on, or detached stats otherwise
+func libraryStatsOf(component interface{}) *libraryStats {
+	if provider, ok := component.(libraryStatsProvider); ok {
+		return provider.libraryStats()
+	}
+	return &libraryStats{}
+}
+
+func (s *libraryStats) fill(stats *Stats) {
+	stats.SharedConnections = s.sharedConnections.Load()
+	stats.OpenTransactions = s.openTransactions.Load()
+	stats.HeldLocks = s.heldLocks.Load()
+}
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrTransactionOptionsConflict = errors.New("transaction options conflict with active transaction")
+
+type TransactionOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// StatementTimeout limits execution time of SELECT statements within transaction
+	StatementTimeout time.Duration
+}
+
+func (opts TransactionOptions) txOptions() *sql.TxOptions {
+	if opts.Isolation == sql.LevelDefault && !opts.ReadOnly {
+		return nil
+	}
+	return &sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	}
+}
+
+// validateJoin checks that transaction started with active options satisfies requested options
+func (opts TransactionOptions) validateJoin(active TransactionOptions) error {
+	if opts.Isolation != sql.LevelDefault && opts.Isolation != active.Isolation {
+		return fmt.Errorf("%w: requested isolation %s, active %s", ErrTransactionOptionsConflict, opts.Isolation, active.Isolation)
+	}
+	if !opts.ReadOnly && active.ReadOnly {
+		return fmt.Errorf("%w: requested read-write, active read-only", ErrTransactionOptionsConflict)
+	}
+	if opts.StatementTimeout != 0 && opts.StatementTimeout != active.StatementTimeout {
+		return fmt.Errorf("%w: requested statement timeout %s, active %s", ErrTransactionOptionsConflict, opts.StatementTimeout, active.StatementTimeout)
+	}
+	return nil
+}
+
+func (opts TransactionOptions) applySession(ctx context.Context, conn TransactionalConnection) error {
+	if opts.StatementTimeout <= 0 {
+		return nil
+	}
+	_, err := conn.ExecContext(ctx, "SET SESSION MAX_EXECUTION_TIME = "+strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10))
+	return err
+}
+
+func (opts TransactionOptions) resetSession(ctx context.Context, conn TransactionalConnection) error {
+	if opts.StatementTimeout <= 0 {
+		return nil
+	}
+	_, err := conn.ExecContext(ctx, "SET SESSION MAX_EXECUTION_TIME = DEFAULT")
+	return err
+}
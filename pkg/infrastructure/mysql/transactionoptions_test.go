@@ -0,0 +1,68 @@
+package mysql_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+func TestNestedUnitOfWorkRejectsConflictingOptions(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+
+	outer, err := factory.UnitOfWorkWithOptions(newContext(t), mysql.TransactionOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = factory.UnitOfWork(outer.Context())
+	if !errors.Is(err, mysql.ErrTransactionOptionsConflict) {
+		t.Fatalf("expected read-write unit of work to conflict with read-only transaction, got %v", err)
+	}
+	_, err = factory.UnitOfWorkWithOptions(outer.Context(), mysql.TransactionOptions{Isolation: sql.LevelSerializable, ReadOnly: true})
+	if !errors.Is(err, mysql.ErrTransactionOptionsConflict) {
+		t.Fatalf("expected isolation to conflict with active transaction, got %v", err)
+	}
+
+	nested, err := factory.UnitOfWorkWithOptions(outer.Context(), mysql.TransactionOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = nested.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = outer.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNestedUnitOfWorkJoinsWithoutOptions(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+
+	outer, err := factory.UnitOfWork(newContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read-only unit of work may run within read-write transaction
+	nested, err := factory.UnitOfWorkWithOptions(outer.Context(), mysql.TransactionOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nested.Nested() {
+		t.Fatal("expected unit of work to join active transaction")
+	}
+	err = nested.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = outer.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	errNumberLockWaitTimeout = 1205
+	errNumberDeadlock        = 1213
+)
+
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts: 3,
+	Backoff: Backoff{
+		InitialInterval: time.Millisecond * 50,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	},
+}
+
+type Transactor interface {
+	// WithinTransaction passes context of unit of work to f, units of work opened with it join the transaction.
+	// Retryable errors are retried only by the outermost unit of work, since server rolls back whole transaction
+	WithinTransaction(ctx context.Context, f func(ctx context.Context, client ClientContext) error) error
+}
+
+type RetryConfig struct {
+	MaxAttempts int
+	Backoff     Backoff
+}
+
+type Backoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+}
+
+// AttemptError is passed to UnitOfWorkCompleteCallback for every failed attempt
+type AttemptError struct {
+	Attempt int
+	Err     error
+}
+
+func (e *AttemptError) Error() string {
+	return fmt.Sprintf("attempt %d: %s", e.Attempt, e.Err)
+}
+
+func (e *AttemptError) Unwrap() error {
+	return e.Err
+}
+
+func NewTransactor(unitOfWorkFactory UnitOfWorkFactory, config RetryConfig) Transactor {
+	return &transactor{
+		unitOfWorkFactory: unitOfWorkFactory,
+		config:            config,
+	}
+}
+
+type transactor struct {
+	unitOfWorkFactory UnitOfWorkFactory
+	config            RetryConfig
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, f func(ctx context.Context, client ClientContext) error) error {
+	for attempt := 1; ; attempt++ {
+		retryable, err := t.attempt(ctx, attempt, f)
+		if err == nil {
+			return nil
+		}
+		if !retryable || !IsRetryableError(err) || attempt >= t.config.MaxAttempts {
+			return err
+		}
+		err = sleep(ctx, t.config.Backoff.Interval(attempt))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *transactor) attempt(ctx context.Context, attempt int, f func(ctx context.Context, client ClientContext) error) (retryable bool, err error) {
+	uow, err := t.unitOfWorkFactory.UnitOfWork(ctx)
+	if err != nil {
+		return true, err
+	}
+	// nested unit of work can not be retried alone, since server rolls back whole transaction on deadlock
+	retryable = !uow.Nested()
+
+	err = f(uow.Context(), uow.ClientContext())
+	if err != nil {
+		err = &AttemptError{Attempt: attempt, Err: err}
+	}
+	return retryable, uow.Complete(err)
+}
+
+func IsRetryableError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == errNumberDeadlock || mysqlErr.Number == errNumberLockWaitTimeout
+}
+
+func (b Backoff) Interval(attempt int) time.Duration {
+	interval := float64(b.InitialInterval)
+	for i := 1; i < attempt; i++ {
+		interval *= b.Multiplier
+		if b.MaxInterval > 0 && interval >= float64(b.MaxInterval) {
+			return b.MaxInterval
+		}
+	}
+	return time.Duration(interval)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql/mysqltest"
+)
+
+var errDeadlock = &gomysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+func TestTransactorRetriesRetryableError(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+	transactor := mysql.NewTransactor(factory, mysql.RetryConfig{MaxAttempts: 3})
+
+	attempts := 0
+	err := transactor.WithinTransaction(context.Background(), func(context.Context, mysql.ClientContext) error {
+		attempts++
+		if attempts == 1 {
+			return errDeadlock
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	factory.AssertRolledBack(t, 1)
+	factory.AssertCommitted(t, 1)
+}
+
+func TestTransactorStopsAfterMaxAttempts(t *testing.T) {
+	factory := mysqltest.NewUnitOfWorkFactory()
+	transactor := mysql.NewTransactor(factory, mysql.RetryConfig{MaxAttempts: 3})
+
+	attempts := 0
+	err := transactor.WithinTransaction(context.Background(), func(context.Context, mysql.ClientContext) error {
+		attempts++
+		return errDeadlock
+	})
+	var attemptErr *mysql.AttemptError
+	if !errors.As(err, &attemptErr) || attemptErr.Attempt != 3 || !mysql.IsRetryableError(err) {
+		t.Fatalf("expected retryable error of the third attempt, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestTransactorDoesNotRetryNestedTransaction(t *testing.T) {
+	for name, factory := range map[string]mysql.UnitOfWorkFactory{
+		"fake":   mysqltest.NewUnitOfWorkFactory(),
+		"sqlite": mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(newClient(t)), nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			transactor := mysql.NewTransactor(factory, mysql.RetryConfig{MaxAttempts: 3})
+			outer, err := factory.UnitOfWork(newContext(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attempts := 0
+			err = transactor.WithinTransaction(outer.Context(), func(context.Context, mysql.ClientContext) error {
+				attempts++
+				return errDeadlock
+			})
+			if !mysql.IsRetryableError(err) {
+				t.Fatalf("expected deadlock error, got %v", err)
+			}
+			if attempts != 1 {
+				t.Fatalf("expected nested transaction not to be retried, got %d attempts", attempts)
+			}
+
+			err = outer.Complete(err)
+			if !mysql.IsRetryableError(err) {
+				t.Fatalf("expected deadlock error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		retryable bool
+	}{
+		{err: errDeadlock, retryable: true},
+		{err: &gomysql.MySQLError{Number: 1205}, retryable: true},
+		{err: &mysql.AttemptError{Attempt: 1, Err: errDeadlock}, retryable: true},
+		{err: &gomysql.MySQLError{Number: 1062}, retryable: false},
+		{err: errTest, retryable: false},
+	} {
+		if mysql.IsRetryableError(tc.err) != tc.retryable {
+			t.Errorf("expected %v to be retryable %v", tc.err, tc.retryable)
+		}
+	}
+}
+
+func TestBackoffIntervalGrowsUpToMaxInterval(t *testing.T) {
+	backoff := mysql.Backoff{
+		InitialInterval: time.Millisecond * 50,
+		MaxInterval:     time.Millisecond * 300,
+		Multiplier:      2,
+	}
+	for attempt, expected := range []time.Duration{
+		time.Millisecond * 50,
+		time.Millisecond * 100,
+		time.Millisecond * 200,
+		time.Millisecond * 300,
+		time.Millisecond * 300,
+	} {
+		if interval := backoff.Interval(attempt + 1); interval != expected {
+			t.Errorf("expected interval %s of attempt %d, got %s", expected, attempt+1, interval)
+		}
+	}
+}
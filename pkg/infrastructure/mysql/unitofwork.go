@@ -2,21 +2,52 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrTransactionRollbackOnly = errors.New("transaction marked as rollback only")
+	ErrUnitOfWorkHookFailed    = errors.New("unit of work hook failed")
+	ErrUnitOfWorkLeaked        = errors.New("unit of work leaked")
+	// ErrTransactionFailed is returned when commit or rollback of transaction fails
+	ErrTransactionFailed = errors.New("transaction failed")
 )
 
 type UnitOfWorkFactory interface {
 	UnitOfWork(ctx context.Context) (UnitOfWork, error)
+	UnitOfWorkWithOptions(ctx context.Context, opts TransactionOptions) (UnitOfWork, error)
 }
 
 type UnitOfWork interface {
+	// Complete commits unit of work or rolls it back when err is not nil. Nested unit of work completed with err
+	// is rolled back to its savepoint and does not affect the transaction, the transaction is marked as rollback
+	// only when rolling back to savepoint fails. The outermost unit of work completed without err rolls back
+	// transaction marked as rollback only and returns ErrTransactionRollbackOnly
 	Complete(err error) error
+	// SetRollbackOnly marks the transaction as rollback only, e.g. when failure of nested unit of work
+	// must roll back the whole transaction
+	SetRollbackOnly()
+	// Nested tells whether unit of work joined transaction of another unit of work of its scope
+	Nested() bool
 	ClientContext() ClientContext
+	// Context carries unit of work scope, nested units of work created with it join the transaction
+	Context() context.Context
+	// OnCommit registers hook executed after the outermost unit of work commits
+	OnCommit(hook UnitOfWorkHook)
+	// OnRollback registers hook executed after the transaction or the savepoint of nested unit of work rolls back
+	OnRollback(hook UnitOfWorkHook)
 }
 
+// UnitOfWorkCompleteCallback is called on completion of every unit of work, nested ones included.
+// err of committed transaction may be not nil, e.g. when OnCommit hook fails with ErrUnitOfWorkHookFailed
 type UnitOfWorkCompleteCallback func(ctx context.Context, err error)
 
+type UnitOfWorkHook func(ctx context.Context) error
+
 func NewUnitOfWorkFactory(
 	connectionPool ConnectionPool,
 	unitOfWorkCompleteCallback UnitOfWorkCompleteCallback,
@@ -24,28 +55,58 @@ func NewUnitOfWorkFactory(
 	return &unitOfWorkFactory{
 		connectionPool:             connectionPool,
 		unitOfWorkCompleteCallback: unitOfWorkCompleteCallback,
-		transactionPool:            make(map[context.Context]*sharedTransaction),
+		stats:                      libraryStatsOf(connectionPool),
+	}
+}
+
+func NewUnitOfWorkFactoryWithLeakDetector(
+	connectionPool ConnectionPool,
+	unitOfWorkCompleteCallback UnitOfWorkCompleteCallback,
+	config LeakDetectorConfig,
+) UnitOfWorkFactory {
+	return &unitOfWorkFactory{
+		connectionPool:             connectionPool,
+		unitOfWorkCompleteCallback: unitOfWorkCompleteCallback,
+		leakDetector:               newLeakDetector(config),
+		stats:                      libraryStatsOf(connectionPool),
 	}
 }
 
 type unitOfWorkFactory struct {
 	connectionPool             ConnectionPool
 	unitOfWorkCompleteCallback UnitOfWorkCompleteCallback
+	leakDetector               *leakDetector
+	stats                      *libraryStats
+
+	mu sync.Mutex
+}
 
-	mu              sync.Mutex
-	transactionPool map[context.Context]*sharedTransaction
+func (factory *unitOfWorkFactory) UnitOfWork(ctx context.Context) (UnitOfWork, error) {
+	return factory.UnitOfWorkWithOptions(ctx, TransactionOptions{})
 }
 
-func (factory *unitOfWorkFactory) UnitOfWork(ctx context.Context) (uow UnitOfWork, err error) {
+func (factory *unitOfWorkFactory) UnitOfWorkWithOptions(ctx context.Context, opts TransactionOptions) (uow UnitOfWork, err error) {
+	ctx, _ = joinScope(ctx)
+
 	factory.mu.Lock()
 	defer factory.mu.Unlock()
 
-	stx, ok := factory.transactionPool[ctx]
+	stx, ok := loadFromScope[*sharedTransaction](ctx, factory)
 	if ok {
+		err = opts.validateJoin(stx.options)
+		if err != nil {
+			return nil, err
+		}
+		savepoint, err := stx.savepoint(ctx)
+		if err != nil {
+			return nil, err
+		}
 		stx.count++
 		uow = &unitOfWork{
 			ctx:              ctx,
 			tx:               stx,
+			savepoint:        savepoint,
+			hooksMark:        stx.hooks.mark(),
 			completeCallback: factory.unitOfWorkCompleteCallback,
 		}
 	}
@@ -54,19 +115,28 @@ func (factory *unitOfWorkFactory) UnitOfWork(ctx context.Context) (uow UnitOfWor
 		if err != nil {
 			return nil, err
 		}
-		tx, err := conn.BeginTransaction(ctx, nil)
+		err = opts.applySession(ctx, conn)
 		if err != nil {
 			return nil, errors.Join(err, conn.Close())
 		}
+		tx, err := conn.BeginTransaction(ctx, opts.txOptions())
+		if err != nil {
+			return nil, errors.Join(err, opts.resetSession(ctx, conn), conn.Close())
+		}
 		stx = &sharedTransaction{
 			Transaction:      tx,
 			ctx:              ctx,
+			options:          opts,
 			count:            1,
 			conn:             conn,
 			commitCallback:   factory.releaseWithCommit,
 			rollbackCallback: factory.releaseWithRollback,
 		}
-		factory.transactionPool[ctx] = stx
+		storeToScope(ctx, factory, stx)
+		factory.stats.openTransactions.Add(1)
+		if factory.leakDetector != nil {
+			factory.leakDetector.track(stx, factory.rollbackLeaked)
+		}
 		uow = &unitOfWork{
 			ctx:              ctx,
 			tx:               stx,
@@ -76,39 +146,81 @@ func (factory *unitOfWorkFactory) UnitOfWork(ctx context.Context) (uow UnitOfWor
 	return uow, nil
 }
 
-func (factory *unitOfWorkFactory) releaseWithCommit(ctx context.Context) error {
-	return factory.releaseWithCallback(ctx, func(stx *sharedTransaction) error {
-		return stx.Transaction.Commit()
-	})
+func (factory *unitOfWorkFactory) releaseWithCommit(stx *sharedTransaction) error {
+	return factory.release(stx, false)
+}
+
+func (factory *unitOfWorkFactory) releaseWithRollback(stx *sharedTransaction) error {
+	return factory.release(stx, true)
 }
 
-func (factory *unitOfWorkFactory) releaseWithRollback(ctx context.Context) error {
-	return factory.releaseWithCallback(ctx, func(stx *sharedTransaction) error {
-		return stx.Transaction.Rollback()
-	})
+func (factory *unitOfWorkFactory) release(stx *sharedTransaction, rollback bool) error {
+	committed, hooks, err := factory.releaseTransaction(stx, rollback)
+	// hooks run without lock, since they may open units of work
+	err = errors.Join(err, runHooks(stx.ctx, hooks))
+	if committed && err != nil {
+		return &committedError{err: err}
+	}
+	return err
 }
 
-func (factory *unitOfWorkFactory) releaseWithCallback(ctx context.Context, f func(stx *sharedTransaction) error) error {
+func (factory *unitOfWorkFactory) releaseTransaction(stx *sharedTransaction, rollback bool) (bool, []UnitOfWorkHook, error) {
 	factory.mu.Lock()
 	defer factory.mu.Unlock()
 
-	stx, ok := factory.transactionPool[ctx]
-	if !ok {
-		return nil
+	if stx.leaked {
+		return false, nil, ErrUnitOfWorkLeaked
+	}
+	if stx.count == 0 {
+		return false, nil, nil
 	}
 	if stx.count == 1 {
-		err := f(stx)
-		err = errors.Join(err, stx.conn.Close())
-		delete(factory.transactionPool, ctx)
-		return err
+		committed, err := stx.finish(rollback)
+		return committed, stx.hooks.take(committed), errors.Join(err, factory.closeTransaction(stx))
+	}
+	if rollback {
+		stx.rollbackOnly.Store(true)
 	}
 	stx.count--
-	return nil
+	return false, nil, nil
+}
+
+func (factory *unitOfWorkFactory) rollbackLeaked(stx *sharedTransaction) error {
+	hooks, err := factory.rollbackLeakedTransaction(stx)
+	return errors.Join(err, runHooks(detach(stx.ctx), hooks))
+}
+
+func (factory *unitOfWorkFactory) rollbackLeakedTransaction(stx *sharedTransaction) ([]UnitOfWorkHook, error) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	if stx.count == 0 {
+		return nil, nil
+	}
+	err := stx.Transaction.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		// transaction is already rolled back by database/sql when its context is done
+		err = nil
+	}
+	stx.leaked = true
+	return stx.hooks.take(false), errors.Join(err, factory.closeTransaction(stx))
+}
+
+func (factory *unitOfWorkFactory) closeTransaction(stx *sharedTransaction) error {
+	stx.count = 0
+	deleteFromScope(stx.ctx, factory)
+	factory.stats.openTransactions.Add(-1)
+	if factory.leakDetector != nil {
+		factory.leakDetector.untrack(stx)
+	}
+	return errors.Join(stx.options.resetSession(detach(stx.ctx), stx.conn), stx.conn.Close())
 }
 
 type unitOfWork struct {
 	ctx              context.Context
-	tx               Transaction
+	tx               *sharedTransaction
+	savepoint        string
+	hooksMark        hooksMark
 	completeCallback UnitOfWorkCompleteCallback
 }
 
@@ -120,6 +232,11 @@ func (u *unitOfWork) Complete(err error) (resultErr error) {
 		}
 	}()
 
+	if u.savepoint != "" {
+		resultErr = u.completeSavepoint(resultErr)
+		return resultErr
+	}
+
 	if resultErr != nil {
 		rollbackErr := u.tx.Rollback()
 		if rollbackErr != nil {
@@ -132,23 +249,175 @@ func (u *unitOfWork) Complete(err error) (resultErr error) {
 	return resultErr
 }
 
+// completeSavepoint keeps the shared transaction committable when nested work is undone by its savepoint,
+// otherwise the shared transaction is marked as rollback only
+func (u *unitOfWork) completeSavepoint(err error) error {
+	if err != nil {
+		_, rollbackErr := u.tx.ExecContext(u.ctx, "ROLLBACK TO SAVEPOINT "+u.savepoint)
+		if rollbackErr != nil {
+			return errors.Join(err, rollbackErr, u.tx.Rollback())
+		}
+		hooksErr := runHooks(u.ctx, u.tx.hooks.rollbackSince(u.hooksMark))
+		return errors.Join(err, hooksErr, u.tx.Commit())
+	}
+	_, releaseErr := u.tx.ExecContext(u.ctx, "RELEASE SAVEPOINT "+u.savepoint)
+	if releaseErr != nil {
+		return errors.Join(releaseErr, u.tx.Rollback())
+	}
+	return u.tx.Commit()
+}
+
 func (u *unitOfWork) ClientContext() ClientContext {
 	return u.tx
 }
 
+func (u *unitOfWork) Context() context.Context {
+	return u.ctx
+}
+
+func (u *unitOfWork) SetRollbackOnly() {
+	u.tx.rollbackOnly.Store(true)
+}
+
+func (u *unitOfWork) Nested() bool {
+	return u.savepoint != ""
+}
+
+func (u *unitOfWork) OnCommit(hook UnitOfWorkHook) {
+	u.tx.hooks.onCommit(hook)
+}
+
+func (u *unitOfWork) OnRollback(hook UnitOfWorkHook) {
+	u.tx.hooks.onRollback(hook)
+}
+
+// committedError is returned when transaction is committed, but hooks or releasing connection failed afterwards
+type committedError struct {
+	err error
+}
+
+func (e *committedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *committedError) Unwrap() error {
+	return e.err
+}
+
 type sharedTransaction struct {
 	Transaction
 	ctx              context.Context
+	options          TransactionOptions
 	count            int
+	savepointSeq     int
+	rollbackOnly     atomic.Bool
+	leaked           bool
+	hooks            completionHooks
 	conn             TransactionalConnection
-	commitCallback   func(ctx context.Context) error
-	rollbackCallback func(ctx context.Context) error
+	commitCallback   func(tx *sharedTransaction) error
+	rollbackCallback func(tx *sharedTransaction) error
 }
 
 func (tx *sharedTransaction) Commit() error {
-	return tx.commitCallback(tx.ctx)
+	return tx.commitCallback(tx)
 }
 
 func (tx *sharedTransaction) Rollback() error {
-	return tx.rollbackCallback(tx.ctx)
+	return tx.rollbackCallback(tx)
+}
+
+func (tx *sharedTransaction) finish(rollback bool) (committed bool, err error) {
+	if rollback || tx.rollbackOnly.Load() {
+		err = tx.Transaction.Rollback()
+		if err != nil {
+			err = errors.Join(ErrTransactionFailed, err)
+		}
+		if !rollback {
+			err = errors.Join(ErrTransactionRollbackOnly, err)
+		}
+		return false, err
+	}
+	err = tx.Transaction.Commit()
+	if err != nil {
+		return false, errors.Join(ErrTransactionFailed, err)
+	}
+	return true, nil
+}
+
+func (tx *sharedTransaction) savepoint(ctx context.Context) (string, error) {
+	tx.savepointSeq++
+	savepoint := fmt.Sprintf("sp_%d", tx.savepointSeq)
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint)
+	if err != nil {
+		return "", err
+	}
+	return savepoint, nil
+}
+
+type completionHooks struct {
+	mu       sync.Mutex
+	commit   []UnitOfWorkHook
+	rollback []UnitOfWorkHook
+}
+
+type hooksMark struct {
+	commit   int
+	rollback int
+}
+
+func (h *completionHooks) onCommit(hook UnitOfWorkHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commit = append(h.commit, hook)
+}
+
+func (h *completionHooks) onRollback(hook UnitOfWorkHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rollback = append(h.rollback, hook)
+}
+
+func (h *completionHooks) mark() hooksMark {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return hooksMark{commit: len(h.commit), rollback: len(h.rollback)}
+}
+
+// rollbackSince drops hooks registered after mark and returns rollback hooks among them
+func (h *completionHooks) rollbackSince(m hooksMark) []UnitOfWorkHook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.commit) > m.commit {
+		h.commit = h.commit[:m.commit]
+	}
+	if len(h.rollback) <= m.rollback {
+		return nil
+	}
+	hooks := append([]UnitOfWorkHook(nil), h.rollback[m.rollback:]...)
+	h.rollback = h.rollback[:m.rollback]
+	return hooks
+}
+
+func (h *completionHooks) take(committed bool) []UnitOfWorkHook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hooks := h.rollback
+	if committed {
+		hooks = h.commit
+	}
+	h.commit, h.rollback = nil, nil
+	return hooks
+}
+
+func runHooks(ctx context.Context, hooks []UnitOfWorkHook) error {
+	var err error
+	for _, hook := range hooks {
+		err = errors.Join(err, hook(ctx))
+	}
+	if err != nil {
+		return errors.Join(ErrUnitOfWorkHookFailed, err)
+	}
+	return nil
 }
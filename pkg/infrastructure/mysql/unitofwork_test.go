@@ -0,0 +1,324 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-calculator/go-lib/pkg/infrastructure/mysql"
+)
+
+var errTest = errors.New("test error")
+
+func TestNestedUnitOfWorkRollsBackToSavepoint(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+	ctx := newContext(t)
+
+	outer, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, outer.Context(), outer, 1)
+
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, nested.Context(), nested, 2)
+	err = nested.Complete(errTest)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected nested error, got %v", err)
+	}
+
+	err = outer.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItems(t, client, 1)
+}
+
+func TestNestedUnitOfWorkIsRolledBackWithOuter(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+	ctx := newContext(t)
+
+	outer, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, nested.Context(), nested, 1)
+	err = nested.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = outer.Complete(errTest)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected outer error, got %v", err)
+	}
+	assertItems(t, client)
+}
+
+func TestSetRollbackOnlyRollsBackOutermostUnitOfWork(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+	ctx := newContext(t)
+
+	outer, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, outer.Context(), outer, 1)
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested.SetRollbackOnly()
+	err = nested.Complete(errTest)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected nested error, got %v", err)
+	}
+
+	err = outer.Complete(nil)
+	if !errors.Is(err, mysql.ErrTransactionRollbackOnly) {
+		t.Fatalf("expected ErrTransactionRollbackOnly, got %v", err)
+	}
+	assertItems(t, client)
+}
+
+func TestUnitOfWorkJoinsUnitOfWorkOfSameContextWithScope(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+	ctx := mysql.WithScope(newContext(t))
+
+	first, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, first, 1)
+
+	second, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, second, 2)
+	if connectionID(t, ctx, first.ClientContext()) != connectionID(t, ctx, second.ClientContext()) {
+		t.Fatal("expected units of work of the same scope to share connection")
+	}
+
+	err = errors.Join(second.Complete(nil), first.Complete(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItems(t, client, 1, 2)
+}
+
+func TestUnitOfWorkJoinsUnitOfWorkOfDerivedContext(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+
+	outer, err := factory.UnitOfWork(newContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, outer.Context(), outer, 1)
+
+	derived, cancel := context.WithTimeout(outer.Context(), time.Minute)
+	defer cancel()
+	nested, err := factory.UnitOfWork(derived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, derived, nested, 2)
+
+	err = errors.Join(nested.Complete(nil), outer.Complete(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItems(t, client, 1, 2)
+}
+
+func TestUnitOfWorkOfSameContextIsNewAfterCompletion(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+	ctx := newContext(t)
+
+	first, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, first, 1)
+	err = first.Complete(errTest)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected error, got %v", err)
+	}
+
+	second, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, second, 2)
+	err = second.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItems(t, client, 2)
+}
+
+func TestUnitOfWorkSharesSessionWithLockOfSameScope(t *testing.T) {
+	client := newClient(t)
+	pool := mysql.NewConnectionPool(client)
+	locks := mysql.NewLockFactory(pool)
+	factory := mysql.NewUnitOfWorkFactory(pool, nil)
+	ctx := mysql.WithScope(newContext(t))
+
+	lock, err := locks.NewLock(ctx, "order.42", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uow, err := factory.UnitOfWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var holder sql.NullInt64
+	err = uow.ClientContext().GetContext(ctx, &holder, "SELECT IS_USED_LOCK(CONCAT('order.42', '.', DATABASE()))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !holder.Valid || holder.Int64 != connectionID(t, ctx, uow.ClientContext()) {
+		t.Fatalf("expected unit of work to run in session of lock, lock is held by %v", holder)
+	}
+
+	err = errors.Join(uow.Complete(nil), lock.Unlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOnCommitHooksRunAfterOutermostCommit(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+
+	outer, err := factory.UnitOfWork(newContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	outer.OnCommit(func(context.Context) error {
+		events = append(events, "outer commit")
+		return nil
+	})
+	outer.OnRollback(func(context.Context) error {
+		events = append(events, "outer rollback")
+		return nil
+	})
+
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested.OnCommit(func(ctx context.Context) error {
+		events = append(events, "nested commit")
+		// hooks may open units of work once the transaction is released
+		uow, err := factory.UnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		insertItem(t, uow.Context(), uow, 2)
+		return uow.Complete(nil)
+	})
+	insertItem(t, nested.Context(), nested, 1)
+	err = nested.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no hooks before outermost commit, got %v", events)
+	}
+
+	err = outer.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, events, "outer commit", "nested commit")
+	assertItems(t, client, 1, 2)
+}
+
+func TestOnRollbackHooksOfSavepointRunOnNestedRollback(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+
+	outer, err := factory.UnitOfWork(newContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	outer.OnCommit(func(context.Context) error {
+		events = append(events, "outer commit")
+		return nil
+	})
+
+	nested, err := factory.UnitOfWork(outer.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested.OnCommit(func(context.Context) error {
+		events = append(events, "nested commit")
+		return nil
+	})
+	nested.OnRollback(func(context.Context) error {
+		events = append(events, "nested rollback")
+		return nil
+	})
+	err = nested.Complete(errTest)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected nested error, got %v", err)
+	}
+	assertEvents(t, events, "nested rollback")
+
+	err = outer.Complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, events, "nested rollback", "outer commit")
+}
+
+func TestFailedOnCommitHookDoesNotUndoCommit(t *testing.T) {
+	client := newClient(t)
+	factory := mysql.NewUnitOfWorkFactory(mysql.NewConnectionPool(client), nil)
+
+	uow, err := factory.UnitOfWork(newContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uow.OnCommit(func(context.Context) error {
+		return errTest
+	})
+	insertItem(t, uow.Context(), uow, 1)
+
+	err = uow.Complete(nil)
+	if !errors.Is(err, mysql.ErrUnitOfWorkHookFailed) || !errors.Is(err, errTest) {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+	assertItems(t, client, 1)
+}
+
+func assertEvents(t *testing.T, events []string, expected ...string) {
+	t.Helper()
+	if len(events) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	for i := range events {
+		if events[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"github.com/tss-calculator/go-lib/pkg/common/maybe"
 )
 
-var (
-	ctxTraceID = struct{}{}
-	ctxDepth   = struct{}{}
+type ctxKey int
+
+const (
+	ctxTraceID ctxKey = iota
+	ctxDepth
 )
 
 type Trace struct {